@@ -62,6 +62,30 @@ func TestRead64(t *testing.T) {
 	}
 }
 
+func TestWrite16(t *testing.T) {
+	for _, v := range []uint16{0, 0xCDAB, math.MaxUint16} {
+		t.Run(fmt.Sprintf("%d", v), func(t *testing.T) {
+			data := make([]byte, 2)
+			write16(data, v)
+			if read16(data) != v {
+				t.Errorf("write16(%04X) == % X", v, data)
+			}
+		})
+	}
+}
+
+func TestWrite64(t *testing.T) {
+	for _, v := range []uint64{0, 0xEFCDAB8967452301, math.MaxUint64} {
+		t.Run(fmt.Sprintf("%d", v), func(t *testing.T) {
+			data := make([]byte, 8)
+			write64(data, v)
+			if read64(data) != v {
+				t.Errorf("write64(%016X) == % X", v, data)
+			}
+		})
+	}
+}
+
 func readBytes(r io.Reader) ([]byte, error) {
 	var data []byte
 	for {
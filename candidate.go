@@ -0,0 +1,148 @@
+package crossword
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type (
+	// Candidate is a key that unlocks a scrambled puzzle according to its
+	// 16-bit scrambled checksum, together with an assessment of the solution it produces.
+	Candidate struct {
+		Key int
+		// Solution is the unscrambled solution, in the form returned by Puzzle.Solution.
+		Solution string
+		// FillConflicts is the number of squares filled in by the player
+		// whose entries differ from the unscrambled solution.
+		FillConflicts int
+		// Plausibility is the fraction of answers in the unscrambled solution
+		// that are accepted as words, from 0 to 1.
+		Plausibility float64
+	}
+
+	// WordChecker reports whether an answer is an acceptable word.
+	WordChecker func(answer string) bool
+)
+
+// UnlockCandidates tries every key in parallel, as UnlockContext does,
+// and returns all those whose unscrambled solution matches the scrambled checksum,
+// most plausible first.
+// Keys that produce the same solution are reported once, using the smallest key.
+// Candidates are ranked by the number of conflicts with the player's fill,
+// then by the fraction of their answers accepted by opts.Words.
+// The puzzle itself is not modified; use UnlockWithKey to apply a candidate.
+func (p *Puzzle) UnlockCandidates(ctx context.Context, opts UnlockOptions) ([]Candidate, error) {
+	if !p.Scrambled {
+		return nil, fmt.Errorf("puzzle is not locked")
+	}
+	words := opts.Words
+	if words == nil {
+		words = looksLikeWord
+	}
+	src, err := p.scrambledLetters()
+	if err != nil {
+		return nil, err
+	}
+	keys, err := p.findKeys(ctx, src, opts, true)
+	if err != nil {
+		return nil, err
+	}
+	dst := make([]byte, len(src))
+	tmp := make([]byte, len(src))
+	seen := make(map[string]bool)
+	var v []Candidate
+	for _, k := range keys {
+		key, _ := NewKeyFromInt(k)
+		unscramble(src, key, dst, tmp)
+		if seen[string(dst)] {
+			continue
+		}
+		seen[string(dst)] = true
+		g := p.expandBuffer(dst)
+		v = append(v, Candidate{
+			Key:           k,
+			Solution:      g.String(),
+			FillConflicts: p.fillConflicts(g),
+			Plausibility:  p.plausibility(g, words),
+		})
+	}
+	sort.SliceStable(v, func(i, j int) bool {
+		if v[i].FillConflicts != v[j].FillConflicts {
+			return v[i].FillConflicts < v[j].FillConflicts
+		}
+		return v[i].Plausibility > v[j].Plausibility
+	})
+	return v, nil
+}
+
+// fillConflicts counts the squares where the player's fill differs from g.
+func (p *Puzzle) fillConflicts(g Grid) int {
+	n := 0
+	for y := 0; y < p.Height; y++ {
+		for x := 0; x < p.Width; x++ {
+			if p.IsBlack(x, y) || !p.IsFilled(x, y) {
+				continue
+			}
+			if p.Fill(x, y) != g[y][x] {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// plausibility returns the fraction of the answers in g that are accepted by words.
+func (p *Puzzle) plausibility(g Grid, words WordChecker) float64 {
+	total, ok := 0, 0
+	for _, d := range p.Dir {
+		for _, word := range d.Words {
+			var sb strings.Builder
+			for _, pos := range word {
+				sb.WriteByte(g[pos.Y][pos.X])
+			}
+			total++
+			if words(sb.String()) {
+				ok++
+			}
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(ok) / float64(total)
+}
+
+// looksLikeWord is a rough test for English-like letter patterns:
+// the answer must contain a vowel (counting Y), and must not contain
+// more than 5 consonants or 3 identical letters in a row.
+func looksLikeWord(answer string) bool {
+	hasVowel := false
+	consonants, repeats := 0, 0
+	for i := 0; i < len(answer); i++ {
+		c := answer[i]
+		if !isScrambledLetter(c) {
+			consonants, repeats = 0, 0
+			continue
+		}
+		if strings.IndexByte("AEIOUY", c) != -1 {
+			hasVowel = true
+			consonants = 0
+		} else {
+			consonants++
+			if consonants > 5 {
+				return false
+			}
+		}
+		if i > 0 && answer[i-1] == c {
+			repeats++
+		} else {
+			repeats = 1
+		}
+		if repeats >= 3 {
+			return false
+		}
+	}
+	return hasVowel
+}
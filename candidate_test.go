@@ -0,0 +1,124 @@
+package crossword
+
+import (
+	"context"
+	"path"
+	"testing"
+)
+
+func TestUnlockCandidates(t *testing.T) {
+	for _, c := range unlockCases {
+		t.Run(c.file, func(t *testing.T) {
+			p, err := Read(path.Join(testDataDir, c.file))
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			v, err := p.UnlockCandidates(context.Background(), UnlockOptions{})
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			if !p.Scrambled {
+				t.Errorf("Scrambled flag is false after listing candidates")
+			}
+			if v[0].Key != c.key {
+				t.Errorf("best candidate has key %04d, want %04d", v[0].Key, c.key)
+			}
+			if v[0].Solution != c.unlocked {
+				t.Errorf("best candidate solution is not correct")
+			}
+			for _, cand := range v[1:] {
+				if cand.Plausibility >= v[0].Plausibility {
+					t.Errorf("candidate %04d has plausibility %.3f, best has %.3f", cand.Key, cand.Plausibility, v[0].Plausibility)
+				}
+			}
+		})
+	}
+}
+
+func TestUnlockCandidatesAmbiguous(t *testing.T) {
+	// Two keys match the scrambled checksum of this puzzle.
+	const (
+		file     = "Apr2510.puz"
+		key      = 4462
+		otherKey = 7606
+	)
+	p, err := Read(path.Join(testDataDir, file))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	v, err := p.UnlockCandidates(context.Background(), UnlockOptions{})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(v) != 2 || v[0].Key != key || v[1].Key != otherKey {
+		t.Fatalf("candidates == %+v, want keys %04d and %04d", v, key, otherKey)
+	}
+
+	// A word list containing the correct answers makes them all plausible.
+	q, err := Read(path.Join(testDataDir, file))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	err = q.UnlockWithKey(key)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	words := make(map[string]bool)
+	for _, d := range q.Dir {
+		for _, answer := range d.Answers {
+			words[answer] = true
+		}
+	}
+	v, err = p.UnlockCandidates(context.Background(), UnlockOptions{Words: func(s string) bool { return words[s] }})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if v[0].Key != key || v[0].Plausibility != 1 {
+		t.Errorf("best candidate is %04d with plausibility %.3f, want %04d with plausibility 1", v[0].Key, v[0].Plausibility, key)
+	}
+
+	// Player's entries that agree with the other solution outweigh plausibility.
+	other := v[1].Solution
+	n := 0
+	for y := 0; y < p.Height; y++ {
+		for x := 0; x < p.Width; x++ {
+			c := other[y*(p.Width+1)+x]
+			if !p.IsBlack(x, y) {
+				p.SetFill(x, y, c)
+				n++
+			}
+		}
+	}
+	v, err = p.UnlockCandidates(context.Background(), UnlockOptions{})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if v[0].Key != otherKey || v[0].FillConflicts != 0 {
+		t.Errorf("best candidate is %04d with %d fill conflicts, want %04d with none", v[0].Key, v[0].FillConflicts, otherKey)
+	}
+	if v[1].FillConflicts == 0 || v[1].FillConflicts > n {
+		t.Errorf("candidate %04d has %d fill conflicts", v[1].Key, v[1].FillConflicts)
+	}
+}
+
+func TestLooksLikeWord(t *testing.T) {
+	cases := []struct {
+		s    string
+		want bool
+	}{
+		{"CROSSWORD", true},
+		{"STRENGTHS", true},
+		{"SKY", true},
+		{"NTH", false},
+		{"XQZRT", false},
+		{"AAAH", false},
+		{"BCDFGHA", false},
+	}
+	for _, c := range cases {
+		if got := looksLikeWord(c.s); got != c.want {
+			t.Errorf("looksLikeWord(%q) == %v, want %v", c.s, got, c.want)
+		}
+	}
+}
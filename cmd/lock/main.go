@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+
+	"github.com/ecc1/crossword"
+)
+
+var forceFlag = flag.Bool("f", false, "force overwriting of the output file, or of the input file if no output file is given")
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] file.puz key output.puz\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s -f file.puz key\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	var input, output string
+	switch flag.NArg() {
+	case 2:
+		input = flag.Arg(0)
+		output = input
+		if !*forceFlag {
+			fail(fmt.Errorf("no output file; use \"-f\" to overwrite %s", input))
+		}
+	case 3:
+		input = flag.Arg(0)
+		output = flag.Arg(2)
+		if exists(output) && !*forceFlag {
+			fail(fmt.Errorf("output file %s already exists; use \"-f\" to overwrite", output))
+		}
+	default:
+		flag.Usage()
+		os.Exit(1)
+	}
+	key, err := strconv.Atoi(flag.Arg(1))
+	if err != nil {
+		fail(err)
+	}
+	puz, err := crossword.Read(input)
+	if err != nil {
+		fail(err)
+	}
+	solution := puz.Solution()
+	err = puz.Lock(key)
+	if err != nil {
+		fail(err)
+	}
+	data, err := crossword.Encode(puz)
+	if err != nil {
+		fail(err)
+	}
+	err = verify(data, key, solution)
+	if err != nil {
+		fail(err)
+	}
+	err = ioutil.WriteFile(output, data, 0644)
+	if err != nil {
+		fail(err)
+	}
+}
+
+// verify checks that the encoded puzzle unlocks with the given key
+// to produce the original solution.
+func verify(data []byte, key int, solution string) error {
+	puz, err := crossword.Decode(data)
+	if err != nil {
+		return fmt.Errorf("locked puzzle cannot be decoded: %w", err)
+	}
+	err = puz.UnlockWithKey(key)
+	if err != nil {
+		return fmt.Errorf("locked puzzle cannot be unlocked: %w", err)
+	}
+	if puz.Solution() != solution {
+		return fmt.Errorf("locked puzzle does not unlock to the original solution")
+	}
+	return nil
+}
+
+func exists(file string) bool {
+	_, err := os.Stat(file)
+	return !os.IsNotExist(err)
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "%s: %s\n", os.Args[0], err)
+	os.Exit(1)
+}
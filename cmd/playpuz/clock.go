@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+)
+
+var (
+	clockLabel   *gtk.Label
+	clockRunning bool
+	elapsed      int // seconds
+)
+
+func makeClock() gtk.IWidget {
+	clockLabel, _ = gtk.LabelNew("")
+	return clockLabel
+}
+
+// startClock resumes the clock from the time stored in the puzzle, if any.
+func startClock() {
+	if puz.Timer != nil {
+		elapsed = puz.Timer.Elapsed
+	}
+	clockRunning = !puzzleIsSolved()
+	showClock()
+	glib.TimeoutSecondsAdd(1, tick)
+}
+
+func stopClock() {
+	clockRunning = false
+}
+
+func tick() bool {
+	if clockRunning {
+		elapsed++
+		showClock()
+	}
+	// Keep the timeout active.
+	return true
+}
+
+func showClock() {
+	h, m, s := elapsed/3600, elapsed/60%60, elapsed%60
+	if h != 0 {
+		clockLabel.SetText(fmt.Sprintf("%d:%02d:%02d", h, m, s))
+	} else {
+		clockLabel.SetText(fmt.Sprintf("%d:%02d", m, s))
+	}
+}
@@ -43,11 +43,13 @@ var keyAction = map[uint]func(){
 	gdk.KEY_Up:        moveUp,
 	gdk.KEY_Right:     moveRight,
 	gdk.KEY_Down:      moveDown,
+	gdk.KEY_Insert:    enterRebus,
+	gdk.KEY_Escape:    enterRebus,
 }
 
 func updateWith(c uint) func() {
 	return func() {
-		updateSquare(c)
+		updateSquare(string(rune(c)))
 		moveForward(true)
 	}
 }
@@ -11,13 +11,14 @@ const (
 	Across = crossword.Across
 	Down   = crossword.Down
 
-	blackSquare = '.'
-	emptySquare = ' '
-	wrongSquare = '?'
+	blackSquare = "."
+	emptySquare = " "
+	wrongSquare = "?"
 )
 
 var (
-	cells        crossword.Grid
+	// Contents of each square, which may be more than one letter for a rebus.
+	cells        [][]string
 	homePos      crossword.Position
 	endPos       crossword.Position
 	cur          crossword.Position
@@ -26,13 +27,17 @@ var (
 )
 
 func initGame() {
-	cells = puz.MakeGrid()
+	cells = make([][]string, puz.Height)
 	for y := 0; y < puz.Height; y++ {
-		cells[y] = make([]byte, puz.Width)
+		cells[y] = make([]string, puz.Width)
 		for x := 0; x < puz.Width; x++ {
-			if puz.IsBlack(x, y) {
+			switch {
+			case puz.IsBlack(x, y):
 				cells[y][x] = blackSquare
-			} else {
+			case puz.IsFilled(x, y):
+				// Resume from the player's saved progress.
+				cells[y][x] = puz.FillString(x, y)
+			default:
 				cells[y][x] = emptySquare
 			}
 		}
@@ -45,8 +50,17 @@ func initGame() {
 	endPos = d.Positions[lastNum]
 }
 
+// getContents returns the contents of the grid, one line per row.
+// Only the first letter of a rebus entry is included.
 func getContents() []byte {
-	return cells.Contents()
+	var buf bytes.Buffer
+	for _, row := range cells {
+		for _, s := range row {
+			buf.WriteByte(s[0])
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
 }
 
 func setContents(contents []byte) error {
@@ -57,7 +71,7 @@ func setContents(contents []byte) error {
 	for y := 0; y < puz.Height; y++ {
 		for x := 0; x < puz.Width; x++ {
 			if !puz.IsBlack(x, y) {
-				cells[y][x] = contents[0]
+				cells[y][x] = string(contents[0])
 				redrawSquare(x, y)
 			}
 			contents = contents[1:]
@@ -67,7 +81,14 @@ func setContents(contents []byte) error {
 }
 
 func puzzleIsSolved() bool {
-	return bytes.Equal(getContents(), puz.SolutionBytes())
+	for y := 0; y < puz.Height; y++ {
+		for x := 0; x < puz.Width; x++ {
+			if !puz.IsBlack(x, y) && cells[y][x] != puz.AnswerString(x, y) {
+				return false
+			}
+		}
+	}
+	return true
 }
 
 func moveHome() {
@@ -110,8 +131,8 @@ func moveDown() {
 	moveForward(false)
 }
 
-func updateSquare(c uint) {
-	cells[cur.Y][cur.X] = byte(c)
+func updateSquare(s string) {
+	cells[cur.Y][cur.X] = s
 	redrawSquare(cur.X, cur.Y)
 	if puzzleIsSolved() {
 		winnerWinner()
@@ -136,7 +157,7 @@ func setActivePos(pos crossword.Position) {
 	cur = pos
 	d := &puz.Dir[curDirection]
 	oldWord := curWord
-	num := int(d.Start[cur.Y][cur.X])
+	num := d.Start[cur.Y][cur.X]
 	if num == 0 {
 		// No word in this direction.
 		return
@@ -176,7 +197,7 @@ func positionInWord(pos crossword.Position, word crossword.Word) int {
 
 func moveForward(skip bool) {
 	d := &puz.Dir[curDirection]
-	num := int(d.Start[cur.Y][cur.X])
+	num := d.Start[cur.Y][cur.X]
 	if num == 0 {
 		// No word in this direction.
 		return
@@ -210,7 +231,7 @@ func moveForward(skip bool) {
 
 func moveBackward(skip bool) {
 	d := &puz.Dir[curDirection]
-	num := int(d.Start[cur.Y][cur.X])
+	num := d.Start[cur.Y][cur.X]
 	if num == 0 {
 		// No word in this direction.
 		return
@@ -245,7 +266,7 @@ func activateFromClue(dir crossword.Direction, n int) {
 
 func highlightClues() {
 	for dir, d := range puz.Dir {
-		num := int(d.Start[cur.Y][cur.X])
+		num := d.Start[cur.Y][cur.X]
 		if num == 0 {
 			// No word in this direction.
 			continue
@@ -257,7 +278,7 @@ func highlightClues() {
 
 func checkSquare(x, y int) {
 	c := cells[y][x]
-	if c == emptySquare || c == puz.Answer(x, y) {
+	if c == emptySquare || c == puz.AnswerString(x, y) {
 		return
 	}
 	cells[y][x] = wrongSquare
@@ -284,11 +305,19 @@ func checkPuzzle() {
 func solveWord() {
 	for _, pos := range curWord {
 		x, y := pos.X, pos.Y
-		cells[y][x] = puz.Answer(x, y)
+		cells[y][x] = puz.AnswerString(x, y)
 		redrawSquare(x, y)
 	}
 }
 
 func solvePuzzle() {
-	setContents(puz.SolutionBytes())
+	for y := 0; y < puz.Height; y++ {
+		for x := 0; x < puz.Width; x++ {
+			if puz.IsBlack(x, y) {
+				continue
+			}
+			cells[y][x] = puz.AnswerString(x, y)
+			redrawSquare(x, y)
+		}
+	}
 }
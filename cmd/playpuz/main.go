@@ -13,10 +13,10 @@ var (
 
 func main() {
 	if len(os.Args) != 2 {
-		fail(fmt.Errorf("single PUZ file required"))
+		fail(fmt.Errorf("single puzzle file required"))
 	}
 	var err error
-	puz, err = crossword.Read(os.Args[1])
+	puz, _, err = crossword.LoadFile(os.Args[1])
 	if err != nil {
 		fail(err)
 	}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"io/ioutil"
+	"path"
+	"strings"
 
+	"github.com/ecc1/crossword"
 	"github.com/gotk3/gotk3/gtk"
 )
 
@@ -61,6 +64,11 @@ func makeMenu() {
 	solveMenuItem.Show()
 	menu.Append(solveMenuItem)
 
+	rebusItem, _ := gtk.MenuItemNewWithLabel("Enter rebus")
+	rebusItem.Connect("activate", enterRebus)
+	rebusItem.Show()
+	menu.Append(rebusItem)
+
 	loadPuzzleItem, _ := gtk.MenuItemNewWithLabel("Load")
 	loadPuzzleItem.Connect("activate", loadPuzzle)
 	loadPuzzleItem.Show()
@@ -93,6 +101,35 @@ func showNotepad() {
 	dialog.Destroy()
 }
 
+// enterRebus prompts for a multi-letter entry for the active square.
+func enterRebus() {
+	dialog, _ := gtk.DialogNewWithButtons("Enter Rebus", window, gtk.DIALOG_MODAL,
+		[]interface{}{"Cancel", gtk.RESPONSE_CANCEL},
+		[]interface{}{"OK", gtk.RESPONSE_ACCEPT})
+	dialog.SetDefaultResponse(gtk.RESPONSE_ACCEPT)
+	entry, _ := gtk.EntryNew()
+	entry.SetActivatesDefault(true)
+	if cells[cur.Y][cur.X] != emptySquare {
+		entry.SetText(cells[cur.Y][cur.X])
+	}
+	box, _ := dialog.GetContentArea()
+	box.PackStart(entry, true, true, 0)
+	entry.Show()
+	res := dialog.Run()
+	text, _ := entry.GetText()
+	dialog.Destroy()
+	if res != gtk.RESPONSE_ACCEPT {
+		return
+	}
+	text = strings.ToUpper(strings.TrimSpace(text))
+	if text == "" {
+		eraseSquare()
+		return
+	}
+	updateSquare(text)
+	moveForward(true)
+}
+
 func loadPuzzle() {
 	dialog, _ := gtk.FileChooserDialogNewWith2Buttons("Load Puzzle", window, gtk.FILE_CHOOSER_ACTION_OPEN,
 		"Cancel", gtk.RESPONSE_CANCEL,
@@ -131,12 +168,36 @@ func savePuzzle() {
 	}
 	filename := dialog.GetFilename()
 	dialog.Destroy()
-	err := ioutil.WriteFile(filename, getContents(), 0644)
+	var err error
+	if path.Ext(filename) == ".puz" {
+		err = savePUZ(filename)
+	} else {
+		err = ioutil.WriteFile(filename, getContents(), 0644)
+	}
 	if err != nil {
 		popupError(err)
 	}
 }
 
+// savePUZ writes the puzzle with the current contents of the grid
+// and the elapsed time, so that solving can be resumed later.
+func savePUZ(filename string) error {
+	for y := 0; y < puz.Height; y++ {
+		for x := 0; x < puz.Width; x++ {
+			c := cells[y][x]
+			switch c {
+			case blackSquare:
+			case emptySquare, wrongSquare:
+				puz.ClearFill(x, y)
+			default:
+				puz.SetFillString(x, y, c)
+			}
+		}
+	}
+	puz.Timer = &crossword.Timer{Elapsed: elapsed, Stopped: !clockRunning}
+	return crossword.Write(filename, puz)
+}
+
 func popupError(err error) {
 	dialog := gtk.MessageDialogNew(window, gtk.DIALOG_MODAL, gtk.MESSAGE_ERROR, gtk.BUTTONS_OK, "Error: %s", err)
 	dialog.Run()
@@ -18,6 +18,7 @@ const (
 	innerSep      = 0.075
 	largeFontSize = 0.500
 	smallFontSize = 0.300
+	maxTextWidth  = 1 - 2*innerSep
 
 	minSquareSize = 50
 	clueWidth     = 250
@@ -56,6 +57,7 @@ func initUI() {
 	makeMenu()
 	window.ShowAll()
 	setActivePos(cur)
+	startClock()
 }
 
 func setGeometry() {
@@ -75,13 +77,23 @@ func min(i, j int) int {
 	return j
 }
 
+func max(i, j int) int {
+	if i > j {
+		return i
+	}
+	return j
+}
+
 func makeTopLevel() gtk.IWidget {
 	p, _ := gtk.PanedNew(gtk.ORIENTATION_HORIZONTAL)
 	p.SetWideHandle(true)
 	p.Pack1(makeClues(Across), true, false)
 	q, _ := gtk.PanedNew(gtk.ORIENTATION_HORIZONTAL)
 	q.SetWideHandle(true)
-	q.Pack1(makeGrid(), true, false)
+	g, _ := gtk.BoxNew(gtk.ORIENTATION_VERTICAL, 0)
+	g.PackStart(makeClock(), false, false, 0)
+	g.PackStart(makeGrid(), true, true, 0)
+	q.Pack1(g, true, false)
 	q.Pack2(makeClues(Down), true, false)
 	p.Pack2(q, true, false)
 	return p
@@ -132,12 +144,21 @@ func makeClues(dir crossword.Direction) gtk.IWidget {
 	return b
 }
 
+// numberChars returns the label width needed for the largest clue number in dir
+// plus space with the markup used in makeClue.
+func numberChars(dir crossword.Direction) int {
+	nums := puz.Dir[dir].Numbers
+	if len(nums) == 0 {
+		return 4
+	}
+	return max(4, len(fmt.Sprint(nums[len(nums)-1]))+1)
+}
+
 func makeClue(dir crossword.Direction, n int) gtk.IWidget {
 	cl, _ := gtk.BoxNew(gtk.ORIENTATION_HORIZONTAL, 0)
 	num, _ := gtk.LabelNew("")
 	num.SetMarkup(fmt.Sprintf("<small><b>%d</b></small>  ", n))
-	// Make this wide enough for 3-digit numbers plus space with the above markup.
-	num.SetWidthChars(4)
+	num.SetWidthChars(numberChars(dir))
 	num.SetXAlign(1)
 	num.SetYAlign(0)
 	cl.PackStart(num, false, false, 0)
@@ -233,8 +254,13 @@ func drawSquare(x int, y int, d *gtk.DrawingArea, c *cairo.Context) {
 	// Square contents.
 	c.SelectFontFace(textFont, cairo.FONT_SLANT_NORMAL, cairo.FONT_WEIGHT_NORMAL)
 	c.SetFontSize(largeFontSize)
-	s := fmt.Sprintf("%c", cells[y][x])
+	s := cells[y][x]
 	t := c.TextExtents(s)
+	if t.Width > maxTextWidth {
+		// Shrink rebus entries to fit in the square.
+		c.SetFontSize(largeFontSize * maxTextWidth / t.Width)
+		t = c.TextExtents(s)
+	}
 	// Ignore t.Height so character baselines are aligned.
 	c.MoveTo(0.5-t.Width/2, 0.75)
 	c.ShowText(s)
@@ -254,6 +280,7 @@ func runUI() {
 }
 
 func winnerWinner() {
+	stopClock()
 	dialog := gtk.MessageDialogNewWithMarkup(window, gtk.DIALOG_MODAL, gtk.MESSAGE_INFO, gtk.BUTTONS_OK, "")
 	dialog.SetMarkup("<b>You have solved the puzzle.</b>")
 	dialog.Run()
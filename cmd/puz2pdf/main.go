@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
 
@@ -14,8 +15,15 @@ var (
 	forceFlag  = flag.Bool("f", false, "force overwriting of PDF output file")
 	multiFlag  = flag.Bool("m", false, "generate multiple layouts for debugging")
 	outputFile = flag.String("o", "", "write PDF output to `file`")
+	fontFlag   = flag.String("font", "", "render text with the TrueType font in `file`, for characters that Helvetica lacks")
+	boldFlag   = flag.String("boldfont", "", "render bold text with the TrueType font in `file` (default: the -font file)")
+
+	renderOpts crossword.RenderOptions
 )
 
+// unicodeFont is the family name under which the -font files are added to the PDF document.
+const unicodeFont = "Unicode"
+
 func main() {
 	flag.Parse()
 	if flag.NArg() == 0 {
@@ -40,25 +48,54 @@ func setupOutput() (*gofpdf.Fpdf, error) {
 			return nil, fmt.Errorf("multiple input files; output file must be specified with \"-o\"")
 		}
 		file := flag.Arg(0)
+		if file == "-" {
+			return nil, fmt.Errorf("reading standard input; output file must be specified with \"-o\"")
+		}
 		base := path.Base(file)
 		ext := path.Ext(base)
-		if ext != ".puz" {
-			return nil, fmt.Errorf("%s: file name must end with .puz", file)
+		if _, ok := crossword.FormatForFile(base); !ok {
+			return nil, fmt.Errorf("%s: file name must end with the extension of a puzzle format", file)
 		}
 		*outputFile = base[:len(base)-len(ext)] + ".pdf"
 	}
 	if exists(*outputFile) && !*forceFlag {
 		return nil, fmt.Errorf("output file %s already exists; use \"-f\" to overwrite", *outputFile)
 	}
-	return gofpdf.New("L", "pt", "Letter", ""), nil
+	pdf := gofpdf.New("L", "pt", "Letter", "")
+	if *fontFlag != "" {
+		err := addFont(pdf)
+		if err != nil {
+			return nil, err
+		}
+		renderOpts.Font = unicodeFont
+	}
+	return pdf, nil
+}
+
+// addFont adds the regular and bold fonts specified by the -font and -boldfont flags.
+func addFont(pdf *gofpdf.Fpdf) error {
+	regular, err := ioutil.ReadFile(*fontFlag)
+	if err != nil {
+		return err
+	}
+	bold := regular
+	if *boldFlag != "" {
+		bold, err = ioutil.ReadFile(*boldFlag)
+		if err != nil {
+			return err
+		}
+	}
+	pdf.AddUTF8FontFromBytes(unicodeFont, "", regular)
+	pdf.AddUTF8FontFromBytes(unicodeFont, "B", bold)
+	return pdf.Error()
 }
 
 func puz2pdf(file string, pdf *gofpdf.Fpdf) error {
-	puz, err := crossword.Read(file)
+	puz, err := readPuzzle(file)
 	if err != nil {
 		return err
 	}
-	rc := puz.NewRenderContext(pdf)
+	rc := puz.NewRenderContextWithOptions(pdf, renderOpts)
 	if *multiFlag {
 		rc.RenderAll()
 	} else {
@@ -67,6 +104,19 @@ func puz2pdf(file string, pdf *gofpdf.Fpdf) error {
 	return nil
 }
 
+// readPuzzle reads a puzzle in any supported format from the named file,
+// or from standard input if file is "-".
+func readPuzzle(file string) (*crossword.Puzzle, error) {
+	var p *crossword.Puzzle
+	var err error
+	if file == "-" {
+		p, _, err = crossword.Load(os.Stdin)
+	} else {
+		p, _, err = crossword.LoadFile(file)
+	}
+	return p, err
+}
+
 func exists(file string) bool {
 	_, err := os.Stat(file)
 	return !os.IsNotExist(err)
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ecc1/crossword"
+)
+
+var (
+	toFlag     = flag.String("to", "", "convert to `format` (default: determined by the output file name)")
+	outputDir  = flag.String("d", "", "convert all input files and directories, writing the results to `dir`")
+	forceFlag  = flag.Bool("f", false, "force overwriting of existing output files")
+	verifyFlag = flag.Bool("verify", false, "re-read each output file and compare it with the input")
+	exolveFlag = flag.String("exolve", "", "load the Exolve script and style sheet from `url` (default: the directory containing the page)")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] input output\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] -to format -d dir input ...\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "Formats: %s\n", formatNames())
+	}
+	flag.Parse()
+	if *exolveFlag != "" {
+		setExolveURL(*exolveFlag)
+	}
+	if *outputDir == "" {
+		if flag.NArg() != 2 {
+			flag.Usage()
+			os.Exit(1)
+		}
+		to, err := outputFormat(flag.Arg(1))
+		if err != nil {
+			fail(err)
+		}
+		err = convert(flag.Arg(0), flag.Arg(1), to)
+		if err != nil {
+			fail(err)
+		}
+		return
+	}
+	if flag.NArg() == 0 || *toFlag == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+	to, err := outputFormat("")
+	if err != nil {
+		fail(err)
+	}
+	failed := 0
+	for _, input := range flag.Args() {
+		failed += convertAll(input, to)
+	}
+	if failed != 0 {
+		fmt.Fprintf(os.Stderr, "%s: %d files failed\n", os.Args[0], failed)
+		os.Exit(1)
+	}
+}
+
+// outputFormat returns the format specified by the -to flag,
+// or the one corresponding to the extension of the output file.
+func outputFormat(output string) (crossword.Format, error) {
+	if *toFlag == "" {
+		f, ok := crossword.FormatForFile(output)
+		if !ok {
+			return "", fmt.Errorf("%s: unknown output format; use \"-to\" to specify one", output)
+		}
+		return f, nil
+	}
+	f := crossword.Format(*toFlag)
+	c, ok := f.Codec()
+	if !ok || c.Encode == nil {
+		return "", fmt.Errorf("cannot convert to %q format (formats: %s)", f, formatNames())
+	}
+	return f, nil
+}
+
+// convertAll converts the input file, or all the puzzle files in the input directory
+// and its subdirectories, to the given format in the output directory.
+// It returns the number of files that could not be converted.
+func convertAll(input string, to crossword.Format) int {
+	info, err := os.Stat(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", os.Args[0], err)
+		return 1
+	}
+	if !info.IsDir() {
+		return report(convert(input, outputFile(filepath.Base(input), to), to))
+	}
+	failed := 0
+	err = filepath.Walk(input, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			failed += report(err)
+			return nil
+		}
+		if info.IsDir() || !canDecode(file) {
+			return nil
+		}
+		rel, err := filepath.Rel(input, file)
+		if err != nil {
+			failed += report(err)
+			return nil
+		}
+		failed += report(convert(file, outputFile(rel, to), to))
+		return nil
+	})
+	if err != nil {
+		failed += report(err)
+	}
+	return failed
+}
+
+// canDecode reports whether the file has the extension of a format that can be read.
+func canDecode(file string) bool {
+	f, ok := crossword.FormatForFile(file)
+	if !ok {
+		return false
+	}
+	c, _ := f.Codec()
+	return c.Decode != nil
+}
+
+// outputFile returns the path in the output directory for the input file with the given relative path.
+func outputFile(rel string, to crossword.Format) string {
+	c, _ := to.Codec()
+	ext := ""
+	if len(c.Extensions) != 0 {
+		ext = c.Extensions[0]
+	}
+	rel = strings.TrimSuffix(rel, filepath.Ext(rel)) + ext
+	return filepath.Join(*outputDir, rel)
+}
+
+func report(err error) int {
+	if err == nil {
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "%s: %s\n", os.Args[0], err)
+	return 1
+}
+
+// convert reads the input puzzle, which may be in any supported format,
+// and writes it to the output file in the given format.
+// Either file may be "-" for standard input or output.
+func convert(input, output string, to crossword.Format) error {
+	p, err := readPuzzle(input)
+	if err != nil {
+		return err
+	}
+	data, err := crossword.EncodeFormat(p, to)
+	if err != nil {
+		return fmt.Errorf("%s: %w", input, err)
+	}
+	if *verifyFlag {
+		err = verify(p, data, to)
+		if err != nil {
+			return fmt.Errorf("%s: verifying %s output: %w", input, to, err)
+		}
+	}
+	if output == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if sameFile(input, output) {
+		return fmt.Errorf("%s: output file would overwrite input", input)
+	}
+	if exists(output) && !*forceFlag {
+		return fmt.Errorf("output file %s already exists; use \"-f\" to overwrite", output)
+	}
+	err = os.MkdirAll(filepath.Dir(output), 0755)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(output, data, 0644)
+}
+
+// readPuzzle reads a puzzle from the named file, or from standard input if file is "-".
+func readPuzzle(file string) (*crossword.Puzzle, error) {
+	if file == "-" {
+		p, _, err := crossword.Load(os.Stdin)
+		return p, err
+	}
+	p, _, err := crossword.LoadFile(file)
+	return p, err
+}
+
+// verify decodes the converted puzzle and checks that it matches the original.
+func verify(p *crossword.Puzzle, data []byte, to crossword.Format) error {
+	c, _ := to.Codec()
+	if c.Decode == nil {
+		return fmt.Errorf("%s format cannot be read", to)
+	}
+	q, err := c.Decode(data)
+	if err != nil {
+		return err
+	}
+	return compare(q, p)
+}
+
+// setExolveURL replaces the Exolve encoder with one that loads
+// the Exolve files from the given URL.
+func setExolveURL(url string) {
+	c, _ := crossword.FormatExolve.Codec()
+	opts := crossword.ExolveOptions{ScriptURL: url}
+	c.Encode = func(p *crossword.Puzzle) ([]byte, error) {
+		return crossword.EncodeExolveWithOptions(p, opts)
+	}
+	crossword.RegisterFormat(crossword.FormatExolve, c)
+}
+
+func formatNames() string {
+	var names []string
+	for _, f := range crossword.Formats() {
+		names = append(names, string(f))
+	}
+	return strings.Join(names, ", ")
+}
+
+func exists(file string) bool {
+	_, err := os.Stat(file)
+	return !os.IsNotExist(err)
+}
+
+func sameFile(a, b string) bool {
+	sa, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	sb, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(sa, sb)
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "%s: %s\n", os.Args[0], err)
+	os.Exit(1)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ecc1/crossword"
+)
+
+// maxDifferences is the number of differences reported by compare.
+const maxDifferences = 5
+
+// compare checks that q has the same contents as p,
+// ignoring features that not all formats represent:
+// the player's fill, and the letter stored for a rebus square.
+func compare(q, p *crossword.Puzzle) error {
+	var diffs []string
+	differ := func(format string, args ...interface{}) {
+		diffs = append(diffs, fmt.Sprintf(format, args...))
+	}
+	for _, f := range []struct {
+		name string
+		a, b string
+	}{
+		{"title", q.Title, p.Title},
+		{"author", q.Author, p.Author},
+		{"copyright", q.Copyright, p.Copyright},
+		{"notepad", q.Notepad, p.Notepad},
+	} {
+		if !sameText(f.a, f.b) {
+			differ("%s is %q instead of %q", f.name, f.a, f.b)
+		}
+	}
+	if q.Width != p.Width || q.Height != p.Height {
+		differ("size is %d×%d instead of %d×%d", q.Width, q.Height, p.Width, p.Height)
+		return differences(diffs)
+	}
+	for y := 0; y < p.Height; y++ {
+		for x := 0; x < p.Width; x++ {
+			pos := crossword.NewPosition(x, y)
+			if q.IsBlack(x, y) != p.IsBlack(x, y) {
+				differ("square %v is black in only one puzzle", pos)
+				continue
+			}
+			if q.AnswerString(x, y) != p.AnswerString(x, y) {
+				differ("answer at %v is %q instead of %q", pos, q.AnswerString(x, y), p.AnswerString(x, y))
+			}
+			if q.IsCircled(x, y) != p.IsCircled(x, y) {
+				differ("circle at %v was not preserved", pos)
+			}
+		}
+	}
+	if len(q.AllClues) != len(p.AllClues) {
+		differ("%d clues instead of %d", len(q.AllClues), len(p.AllClues))
+	} else {
+		for i := range p.AllClues {
+			if q.AllClues[i] != p.AllClues[i] {
+				differ("clue %d is %q instead of %q", i+1, q.AllClues[i], p.AllClues[i])
+			}
+		}
+	}
+	return differences(diffs)
+}
+
+func differences(diffs []string) error {
+	switch {
+	case len(diffs) == 0:
+		return nil
+	case len(diffs) > maxDifferences:
+		return fmt.Errorf("%s; and %d more differences", strings.Join(diffs[:maxDifferences], "; "), len(diffs)-maxDifferences)
+	}
+	return fmt.Errorf("%s", strings.Join(diffs, "; "))
+}
+
+// sameText reports whether a and b are equal apart from
+// surrounding white space and the form of line breaks,
+// which not all formats preserve.
+func sameText(a, b string) bool {
+	norm := func(s string) string {
+		return strings.TrimSpace(strings.ReplaceAll(s, "\r\n", "\n"))
+	}
+	return norm(a) == norm(b)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/ecc1/crossword"
+)
+
+var (
+	dryRun = flag.Bool("n", false, "report damaged checksums without rewriting files")
+)
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fail(fmt.Errorf("no input files"))
+	}
+	status := 0
+	for _, file := range flag.Args() {
+		err := puzfix(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", os.Args[0], err)
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
+
+// puzfix rewrites file with corrected checksums if any of them are wrong.
+func puzfix(file string) error {
+	opts := crossword.DecodeOptions{IgnoreChecksums: true}
+	p, diags, err := crossword.ReadWithOptions(file, opts)
+	if err != nil {
+		return err
+	}
+	bad := false
+	for _, d := range diags {
+		if d.Kind == crossword.ChecksumMismatch {
+			bad = true
+			if *dryRun {
+				fmt.Printf("%s: %s\n", file, d)
+			} else {
+				fmt.Printf("%s: fixed %s\n", file, d)
+			}
+		} else {
+			fmt.Printf("%s: warning: %s\n", file, d)
+		}
+	}
+	if !bad {
+		fmt.Printf("%s: checksums OK\n", file)
+		return nil
+	}
+	if *dryRun {
+		return nil
+	}
+	// Encode recomputes extension checksums as it writes each section.
+	_, err = p.FixChecksums()
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	puz, err := crossword.Encode(p)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	return ioutil.WriteFile(file, puz, 0644)
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "%s: %s\n", os.Args[0], err)
+	os.Exit(1)
+}
@@ -3,21 +3,31 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
 
 	"github.com/ecc1/crossword"
 )
 
 var (
-	goFormat = flag.Bool("g", false, "print puzzle in Go struct format")
+	goFormat    = flag.Bool("g", false, "print puzzle in Go struct format")
+	lenientFlag = flag.Bool("l", false, "report checksum mismatches and trailing data as warnings instead of failing")
 )
 
 func main() {
 	flag.Parse()
 	if flag.NArg() != 1 {
-		fail(fmt.Errorf("single PUZ file required"))
+		fail(fmt.Errorf("single puzzle file required"))
+	}
+	opts := crossword.DecodeOptions{
+		IgnoreChecksums:      *lenientFlag,
+		AllowTrailingGarbage: *lenientFlag,
+	}
+	file := flag.Arg(0)
+	p, diags, err := readPuzzle(file, opts)
+	for _, d := range diags {
+		fmt.Fprintf(os.Stderr, "%s: warning: %s: %s\n", os.Args[0], file, d)
 	}
-	p, err := crossword.Read(flag.Arg(0))
 	if err != nil {
 		fail(err)
 	}
@@ -28,6 +38,39 @@ func main() {
 	}
 }
 
+// readPuzzle reads a puzzle in any supported format from the named file,
+// or from standard input if file is "-".
+// Decoding options apply only to PUZ files.
+func readPuzzle(file string, opts crossword.DecodeOptions) (*crossword.Puzzle, []crossword.Diagnostic, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	p, diags, err := decodePuzzle(data, opts)
+	if err != nil {
+		err = fmt.Errorf("%s: %w", file, err)
+	}
+	return p, diags, err
+}
+
+func decodePuzzle(data []byte, opts crossword.DecodeOptions) (*crossword.Puzzle, []crossword.Diagnostic, error) {
+	f, err := crossword.DetectFormat(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if f == crossword.FormatPUZ {
+		return crossword.DecodeWithOptions(data, opts)
+	}
+	p, err := crossword.DecodeFormat(data, f)
+	return p, nil, err
+}
+
 func fail(err error) {
 	fmt.Fprintf(os.Stderr, "%s: %s\n", os.Args[0], err)
 	os.Exit(1)
@@ -1,50 +1,190 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
 
 	"github.com/ecc1/crossword"
 )
 
+var (
+	keyFlag   = flag.Int("k", 0, "unlock with `key` instead of trying all keys")
+	outputDir = flag.String("d", "", "write unlocked PUZ files to `dir` instead of printing solutions")
+)
+
+type result int
+
+const (
+	unlocked result = iota
+	alreadyUnlocked
+	failed
+)
+
 func main() {
-	var file string
-	var key int
-	var err error
-	switch len(os.Args) {
-	case 2:
-		file = os.Args[1]
-	case 3:
-		file = os.Args[1]
-		key, err = strconv.Atoi(os.Args[2])
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] file.puz ...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	files := flag.Args()
+	key := *keyFlag
+	if len(files) == 2 && key == 0 && !exists(files[1]) {
+		// Support the original "unlock file.puz key" form.
+		k, err := strconv.Atoi(files[1])
+		if err == nil {
+			files, key = files[:1], k
+			if key == 0 {
+				fail(fmt.Errorf("0000 is not a valid key"))
+			}
+		}
+	}
+	if len(files) == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	var summary [failed + 1][]string
+	for _, file := range files {
+		r, err := unlock(ctx, file, key, len(files) > 1)
 		if err != nil {
-			fail(err)
+			fmt.Fprintf(os.Stderr, "%s: %s\n", os.Args[0], err)
 		}
-		if key == 0 {
-			fail(fmt.Errorf("0000 is not a valid key"))
+		summary[r] = append(summary[r], file)
+		if ctx.Err() != nil {
+			break
 		}
-	default:
-		fail(fmt.Errorf("Usage: %s file.puz [key]", os.Args[0]))
 	}
-	puz, err := crossword.Read(file)
+	if len(files) > 1 || *outputDir != "" {
+		printSummary(summary[unlocked], "unlocked")
+		printSummary(summary[alreadyUnlocked], "already unlocked")
+		printSummary(summary[failed], "failed")
+	}
+	if len(summary[failed]) != 0 {
+		os.Exit(1)
+	}
+}
+
+// unlock unlocks a single file and either prints its solution
+// or writes it to the output directory.
+func unlock(ctx context.Context, file string, key int, multi bool) (result, error) {
+	puz, err := readPuzzle(file)
 	if err != nil {
-		fail(err)
+		return failed, err
 	}
+	if !puz.Scrambled {
+		if *outputDir == "" {
+			printSolution(file, puz, multi)
+		}
+		return alreadyUnlocked, nil
+	}
+	bruteForced := false
 	if key != 0 {
 		err = puz.UnlockWithKey(key)
 	} else {
-		key, err = puz.Unlock()
-		if err == nil {
-			fmt.Printf("[key = %04d]\n", key)
-		}
+		key, err = bruteForce(ctx, file, puz)
+		bruteForced = true
 	}
 	if err != nil {
-		fail(err)
+		return failed, fmt.Errorf("%s: %w", file, err)
+	}
+	if *outputDir != "" {
+		err = writePuzzle(file, puz)
+		if err != nil {
+			return failed, err
+		}
+		return unlocked, nil
+	}
+	if multi {
+		fmt.Printf("==> %s <==\n", file)
+	}
+	if bruteForced {
+		fmt.Printf("[key = %04d]\n", key)
+	}
+	fmt.Print(puz.Solution())
+	return unlocked, nil
+}
+
+// printSolution prints the solution of a puzzle that is already unlocked.
+func printSolution(file string, puz *crossword.Puzzle, multi bool) {
+	if multi {
+		fmt.Printf("==> %s <==\n", file)
 	}
 	fmt.Print(puz.Solution())
 }
 
+// bruteForce unlocks the puzzle with the most plausible key that matches its scrambled checksum,
+// warning if there is more than one.
+func bruteForce(ctx context.Context, file string, puz *crossword.Puzzle) (int, error) {
+	v, err := puz.UnlockCandidates(ctx, crossword.UnlockOptions{})
+	if err != nil {
+		return 0, err
+	}
+	if len(v) > 1 {
+		fmt.Fprintf(os.Stderr, "%s: warning: %s: %d keys match the scrambled checksum:\n", os.Args[0], file, len(v))
+		for _, c := range v {
+			fmt.Fprintf(os.Stderr, "\t%04d: %.0f%% plausible answers, %d conflicts with fill\n", c.Key, 100*c.Plausibility, c.FillConflicts)
+		}
+	}
+	key := v[0].Key
+	return key, puz.UnlockWithKey(key)
+}
+
+// writePuzzle writes the unlocked puzzle to the output directory,
+// using the same base name as the input file.
+// Encoding the puzzle recomputes its checksums and clears the scrambled flag in the header.
+func writePuzzle(file string, puz *crossword.Puzzle) error {
+	if file == "-" {
+		return fmt.Errorf("cannot name output file for standard input")
+	}
+	out := filepath.Join(*outputDir, filepath.Base(file))
+	if sameFile(file, out) {
+		return fmt.Errorf("%s: output file would overwrite input", file)
+	}
+	err := os.MkdirAll(*outputDir, 0755)
+	if err != nil {
+		return err
+	}
+	return crossword.Write(out, puz)
+}
+
+func printSummary(files []string, what string) {
+	fmt.Printf("%d %s\n", len(files), what)
+	for _, file := range files {
+		fmt.Printf("\t%s\n", file)
+	}
+}
+
+// readPuzzle reads a puzzle from the named file, or from standard input if file is "-".
+func readPuzzle(file string) (*crossword.Puzzle, error) {
+	if file == "-" {
+		return crossword.DecodeReader(os.Stdin)
+	}
+	return crossword.Read(file)
+}
+
+func exists(file string) bool {
+	_, err := os.Stat(file)
+	return !os.IsNotExist(err)
+}
+
+func sameFile(a, b string) bool {
+	sa, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	sb, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(sa, sb)
+}
+
 func fail(err error) {
 	fmt.Fprintf(os.Stderr, "%s: %s\n", os.Args[0], err)
 	os.Exit(1)
@@ -0,0 +1,108 @@
+package crossword
+
+import (
+	"fmt"
+)
+
+type (
+	// DecodeOptions control how a puzzle is decoded.
+	DecodeOptions struct {
+		// Strict causes extension sections with unrecognized codes
+		// to be rejected rather than preserved.
+		Strict bool
+		// IgnoreChecksums causes checksum mismatches to be reported
+		// as diagnostics rather than errors.
+		IgnoreChecksums bool
+		// AllowTrailingGarbage causes data after the last valid extension section
+		// to be reported as a diagnostic rather than an error.
+		AllowTrailingGarbage bool
+	}
+
+	// Diagnostic describes a problem that was tolerated while decoding a puzzle.
+	Diagnostic struct {
+		Kind DiagnosticKind
+		// Section is the part of the puzzle concerned:
+		// HeaderSection, GlobalSection, MagicSection, or an extension code.
+		Section string
+		// Expected and Computed are the stored and actual checksums for a ChecksumMismatch.
+		Expected uint64
+		Computed uint64
+		// Length is the number of bytes of extraneous data for LeadingGarbage and TrailingGarbage.
+		Length int
+	}
+
+	DiagnosticKind int
+
+	decoder struct {
+		opts        DecodeOptions
+		diagnostics []Diagnostic
+	}
+)
+
+const (
+	ChecksumMismatch DiagnosticKind = iota
+	LeadingGarbage
+	TrailingGarbage
+)
+
+func (kind DiagnosticKind) String() string {
+	switch kind {
+	case ChecksumMismatch:
+		return "checksum mismatch"
+	case LeadingGarbage:
+		return "leading garbage"
+	case TrailingGarbage:
+		return "trailing garbage"
+	}
+	return fmt.Sprintf("DiagnosticKind(%d)", int(kind))
+}
+
+func (d Diagnostic) String() string {
+	switch d.Kind {
+	case ChecksumMismatch:
+		e := ChecksumError{Section: d.Section, Got: d.Computed, Want: d.Expected}
+		return e.Error()
+	case LeadingGarbage:
+		return fmt.Sprintf("%d bytes of data before header", d.Length)
+	case TrailingGarbage:
+		if d.Section != "" {
+			return fmt.Sprintf("%d bytes of data after last extension, starting with truncated %s extension", d.Length, d.Section)
+		}
+		return fmt.Sprintf("%d bytes of data after last extension", d.Length)
+	}
+	return d.Kind.String()
+}
+
+func (d *decoder) report(diag Diagnostic) {
+	d.diagnostics = append(d.diagnostics, diag)
+}
+
+// checkChecksum compares a computed checksum with the value stored in the puzzle.
+// A mismatch is an error unless checksums are being ignored,
+// in which case it is reported as a diagnostic.
+func (d *decoder) checkChecksum(section string, computed uint64, expected uint64) error {
+	if computed == expected {
+		return nil
+	}
+	if !d.opts.IgnoreChecksums {
+		return &ChecksumError{Section: section, Got: computed, Want: expected}
+	}
+	d.report(Diagnostic{
+		Kind:     ChecksumMismatch,
+		Section:  section,
+		Expected: expected,
+		Computed: computed,
+	})
+	return nil
+}
+
+// isExtensionCode reports whether code is a plausible extension section code.
+func isExtensionCode(code string) bool {
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		if !('A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return len(code) == 4
+}
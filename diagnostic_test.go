@@ -0,0 +1,155 @@
+package crossword
+
+import (
+	"io/ioutil"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestDiagnostics(t *testing.T) {
+	orig, err := ioutil.ReadFile(path.Join(testDataDir, "Mar1008.puz"))
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	p, err := Decode(orig)
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	global := uint64(p.Checksum.Global)
+	magic := p.Checksum.Magic
+	n := len(orig)
+	cases := []struct {
+		name   string
+		modify func([]byte) []byte
+		opts   DecodeOptions
+		diags  []Diagnostic
+	}{
+		{
+			name:   "clean",
+			modify: func(v []byte) []byte { return v },
+		},
+		{
+			name:   "global",
+			modify: func(v []byte) []byte { v[0] ^= 0x01; return v },
+			opts:   DecodeOptions{IgnoreChecksums: true},
+			diags: []Diagnostic{
+				{Kind: ChecksumMismatch, Section: GlobalSection, Expected: global ^ 0x01, Computed: global},
+			},
+		},
+		{
+			name:   "magic",
+			modify: func(v []byte) []byte { v[23] ^= 0x80; return v },
+			opts:   DecodeOptions{IgnoreChecksums: true},
+			diags: []Diagnostic{
+				{Kind: ChecksumMismatch, Section: MagicSection, Expected: magic ^ 0x80<<56, Computed: magic},
+			},
+		},
+		{
+			name:   "extension",
+			modify: func(v []byte) []byte { v[n-4] = '9'; return v },
+			opts:   DecodeOptions{IgnoreChecksums: true},
+			diags: []Diagnostic{
+				{Kind: ChecksumMismatch, Section: "LTIM", Expected: ltim("8,0"), Computed: ltim("9,0")},
+			},
+		},
+		{
+			name:   "leading",
+			modify: func(v []byte) []byte { return append([]byte("junk"), v...) },
+			diags: []Diagnostic{
+				{Kind: LeadingGarbage, Length: 4},
+			},
+		},
+		{
+			name:   "short trailing",
+			modify: func(v []byte) []byte { return append(v, "junk"...) },
+			diags: []Diagnostic{
+				{Kind: TrailingGarbage, Length: 4},
+			},
+		},
+		{
+			name:   "trailing",
+			modify: func(v []byte) []byte { return append(v, "\x00\x01\x02\x03\x04\x05\x06\x07\x08"...) },
+			opts:   DecodeOptions{AllowTrailingGarbage: true},
+			diags: []Diagnostic{
+				{Kind: TrailingGarbage, Length: 9},
+			},
+		},
+		{
+			name:   "truncated extension",
+			modify: func(v []byte) []byte { return append(v, "XTRA\xFF\x00\x00\x00data"...) },
+			opts:   DecodeOptions{AllowTrailingGarbage: true},
+			diags: []Diagnostic{
+				{Kind: TrailingGarbage, Section: "XTRA", Length: 12},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			puz := c.modify(append([]byte{}, orig...))
+			p, diags, err := DecodeWithOptions(puz, c.opts)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			if p.Solution() == "" {
+				t.Errorf("empty solution")
+			}
+			if !reflect.DeepEqual(diags, c.diags) {
+				t.Errorf("diagnostics == %v, want %v", diags, c.diags)
+			}
+			if c.opts == (DecodeOptions{}) {
+				return
+			}
+			_, err = Decode(puz)
+			if err == nil {
+				t.Errorf("Decode succeeded without options")
+			}
+		})
+	}
+}
+
+func TestDiagnosticsClueCount(t *testing.T) {
+	puz, err := ioutil.ReadFile(path.Join(testDataDir, "Mar1008.puz"))
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	puz[46]-- // number of clues
+	opts := DecodeOptions{IgnoreChecksums: true, AllowTrailingGarbage: true}
+	_, _, err = DecodeWithOptions(puz, opts)
+	if err == nil {
+		t.Errorf("decoding puzzle with wrong number of clues succeeded")
+		return
+	}
+	want := "15×15 puzzle has 77 clues for 78 entries"
+	if err.Error() != want {
+		t.Errorf("error == %q, want %q", err, want)
+	}
+}
+
+func ltim(s string) uint64 {
+	return uint64(checksum([]byte(s), 0))
+}
+
+func TestDiagnosticString(t *testing.T) {
+	cases := []struct {
+		diag Diagnostic
+		s    string
+	}{
+		{Diagnostic{Kind: ChecksumMismatch, Section: HeaderSection, Expected: 0x1234, Computed: 0xABCD}, "header checksum = ABCD, expected 1234"},
+		{Diagnostic{Kind: ChecksumMismatch, Section: MagicSection, Expected: 0x1234, Computed: 0xABCD}, "magic checksum = 000000000000ABCD, expected 0000000000001234"},
+		{Diagnostic{Kind: ChecksumMismatch, Section: "GEXT", Expected: 0x1234, Computed: 0xABCD}, "GEXT extension checksum = ABCD, expected 1234"},
+		{Diagnostic{Kind: TrailingGarbage, Length: 3}, "3 bytes of data after last extension"},
+	}
+	for _, c := range cases {
+		t.Run(c.s, func(t *testing.T) {
+			s := c.diag.String()
+			if s != c.s {
+				t.Errorf("String() == %q, want %q", s, c.s)
+			}
+		})
+	}
+}
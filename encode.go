@@ -0,0 +1,339 @@
+package crossword
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+)
+
+const (
+	defaultVersion = "1.3"
+	puzzleType     = 0x0001
+	scrambledTag   = 0x0004
+)
+
+// Write encodes the puzzle in PUZ format and writes it to the named file.
+func Write(file string, p *Puzzle) error {
+	puz, err := Encode(p)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, puz, 0644)
+}
+
+// Encode returns the PUZ representation of the puzzle,
+// with all checksums recomputed from its contents.
+func Encode(p *Puzzle) ([]byte, error) {
+	w, h := p.Width, p.Height
+	if w <= 0 || w > maxGridSize || h <= 0 || h > maxGridSize {
+		return nil, fmt.Errorf("cannot encode %d×%d puzzle", w, h)
+	}
+	if len(p.AllClues) > 0xFFFF {
+		return nil, fmt.Errorf("cannot encode %d clues", len(p.AllClues))
+	}
+	grids, err := p.gridBytes()
+	if err != nil {
+		return nil, err
+	}
+	header := p.checksummedHeader(grids)
+
+	var buf bytes.Buffer
+	buf.Write(header)
+	buf.Write(grids)
+	text := []string{p.Title, p.Author, p.Copyright}
+	text = append(text, p.AllClues...)
+	text = append(text, p.Notepad)
+	for _, s := range text {
+		err := p.writeString(&buf, s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range p.encodedExtensions() {
+		if len(e.Data) > 0xFFFF {
+			return nil, fmt.Errorf("%s extension contains %d bytes of data", e.Code, len(e.Data))
+		}
+		writeExtension(&buf, e.Code, e.Data)
+	}
+	return buf.Bytes(), nil
+}
+
+// FixChecksums recomputes the CIB, global, and magic checksums in the puzzle header
+// from the current contents of the puzzle.
+// It returns a diagnostic for each checksum whose value changed.
+func (p *Puzzle) FixChecksums() ([]Diagnostic, error) {
+	grids, err := p.gridBytes()
+	if err != nil {
+		return nil, err
+	}
+	h := p.checksummedHeader(grids)
+	var old []byte
+	if len(p.Header) == headerLength {
+		old = p.Header
+	} else {
+		old = make([]byte, headerLength)
+	}
+	sums := []struct {
+		section string
+		stored  uint64
+		fixed   uint64
+	}{
+		{HeaderSection, uint64(read16(old[14:16])), uint64(read16(h[14:16]))},
+		{GlobalSection, uint64(p.Checksum.Global), uint64(read16(h[0:2]))},
+		{MagicSection, p.Checksum.Magic, read64(h[16:24])},
+	}
+	var diags []Diagnostic
+	for _, s := range sums {
+		if s.stored == s.fixed {
+			continue
+		}
+		diags = append(diags, Diagnostic{
+			Kind:     ChecksumMismatch,
+			Section:  s.section,
+			Expected: s.stored,
+			Computed: s.fixed,
+		})
+	}
+	p.Header = h
+	p.Checksum.Global = read16(h[0:2])
+	p.Checksum.Magic = read64(h[16:24])
+	return diags, nil
+}
+
+// gridBytes returns the contents of the solution and fill grids
+// in the order they are stored in a PUZ file.
+func (p *Puzzle) gridBytes() ([]byte, error) {
+	w, h := p.Width, p.Height
+	if !p.hasGridSize(p.solution) {
+		return nil, fmt.Errorf("solution does not match %d×%d puzzle", w, h)
+	}
+	fill := p.fill
+	if fill == nil {
+		fill = p.emptyFill()
+	} else if !p.hasGridSize(fill) {
+		return nil, fmt.Errorf("fill does not match %d×%d puzzle", w, h)
+	}
+	return append(p.solution.bytes(), fill.bytes()...), nil
+}
+
+// checksummedHeader returns a header for the puzzle, as made by makeHeader,
+// with the CIB, global, and magic checksums filled in.
+func (p *Puzzle) checksummedHeader(grids []byte) []byte {
+	h := p.makeHeader()
+	cib := cibChecksum(h)
+	write16(h[0:2], p.globalChecksum(cib, grids))
+	write16(h[14:16], cib)
+	write64(h[16:24], p.magicChecksum(cib, grids))
+	return h
+}
+
+// makeHeader returns a copy of the puzzle header (or a new one, if there is none)
+// updated with the current puzzle dimensions, version, and flags.
+// The checksums covering the rest of the file are not filled in.
+func (p *Puzzle) makeHeader() []byte {
+	h := make([]byte, headerLength)
+	if len(p.Header) == headerLength {
+		copy(h, p.Header)
+	} else {
+		write16(h[48:50], puzzleType)
+	}
+	copy(h[2:14], magic)
+	version := p.Version
+	if version == "" {
+		version = defaultVersion
+	}
+	copy(h[24:28], version+"\x00")
+	write16(h[30:32], p.Checksum.Scrambled)
+	h[44] = uint8(p.Width)
+	h[45] = uint8(p.Height)
+	write16(h[46:48], uint16(len(p.AllClues)))
+	if !p.Scrambled {
+		write16(h[50:52], 0)
+	} else if read16(h[50:52]) == 0 {
+		write16(h[50:52], scrambledTag)
+	}
+	return h
+}
+
+func (p *Puzzle) hasGridSize(g Grid) bool {
+	if len(g) != p.Height {
+		return false
+	}
+	for _, row := range g {
+		if len(row) != p.Width {
+			return false
+		}
+	}
+	return true
+}
+
+// emptyFill returns a fill grid with no squares filled in.
+func (p *Puzzle) emptyFill() Grid {
+	g := p.MakeGrid()
+	for y := range g {
+		for x := range g[y] {
+			if p.IsBlack(x, y) {
+				g[y][x] = blackSquare
+			} else {
+				g[y][x] = emptySquare
+			}
+		}
+	}
+	return g
+}
+
+// extensionOrder is the order in which AcrossLite writes extension sections.
+var extensionOrder = []string{"GRBS", "RTBL", "LTIM", "GEXT", "RUSR"}
+
+// encodedExtensions returns the extension sections to be encoded:
+// those that were present when the puzzle was decoded, in their original order,
+// followed by any others for which the puzzle now has data.
+// Unrecognized sections are taken in order from p.Extensions.
+func (p *Puzzle) encodedExtensions() []Extension {
+	var v []Extension
+	seen := make(map[string]bool)
+	next := 0
+	for _, code := range p.sections {
+		if !isKnownExtension(code) {
+			if next < len(p.Extensions) {
+				v = append(v, p.Extensions[next])
+				next++
+			}
+			continue
+		}
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		data := p.extensionData(code)
+		if data != nil {
+			v = append(v, Extension{Code: code, Data: data})
+		}
+	}
+	for _, code := range extensionOrder {
+		if seen[code] {
+			continue
+		}
+		data := p.extensionData(code)
+		if data != nil {
+			v = append(v, Extension{Code: code, Data: data})
+		}
+	}
+	return append(v, p.Extensions[next:]...)
+}
+
+func isKnownExtension(code string) bool {
+	for _, c := range extensionOrder {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// extensionData returns the data for the known extension section with the given code,
+// or nil if the section should be omitted.
+func (p *Puzzle) extensionData(code string) []byte {
+	switch code {
+	case "GEXT":
+		if len(p.flags) == 0 {
+			return nil
+		}
+		return p.flags.bytes()
+	case "GRBS":
+		if len(p.rebus) == 0 {
+			return nil
+		}
+		return p.rebus.bytes()
+	case "RTBL":
+		if len(p.rebusTable) == 0 {
+			return nil
+		}
+		return p.rebusTableBytes()
+	case "RUSR":
+		if len(p.userRebus) == 0 {
+			return nil
+		}
+		return p.userRebusBytes()
+	case "LTIM":
+		if p.Timer == nil {
+			return nil
+		}
+		return p.Timer.bytes()
+	}
+	return nil
+}
+
+// rebusTableBytes returns the contents of the RTBL extension,
+// with entries in increasing order of their keys.
+func (p *Puzzle) rebusTableBytes() []byte {
+	keys := make([]int, 0, len(p.rebusTable))
+	for k := range p.rebusTable {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	var buf bytes.Buffer
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "%2d:", k)
+		buf.Write(p.puzzleBytes(p.rebusTable[k]))
+		buf.WriteByte(';')
+	}
+	return buf.Bytes()
+}
+
+// userRebusBytes returns the contents of the RUSR extension.
+func (p *Puzzle) userRebusBytes() []byte {
+	var buf bytes.Buffer
+	for _, row := range p.userRebus {
+		for _, s := range row {
+			buf.Write(p.puzzleBytes(s))
+			buf.WriteByte(0)
+		}
+	}
+	return buf.Bytes()
+}
+
+// bytes returns the contents of the LTIM extension.
+func (t *Timer) bytes() []byte {
+	stopped := 0
+	if t.Stopped {
+		stopped = 1
+	}
+	return []byte(fmt.Sprintf("%d,%d", t.Elapsed, stopped))
+}
+
+func writeExtension(buf *bytes.Buffer, code string, data []byte) {
+	var v [8]byte
+	copy(v[0:4], code)
+	write16(v[4:6], uint16(len(data)))
+	write16(v[6:8], checksum(data, 0))
+	buf.Write(v[:])
+	buf.Write(data)
+	buf.WriteByte(0)
+}
+
+func (p *Puzzle) writeString(buf *bytes.Buffer, s string) error {
+	v, err := p.textEncoding().NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		return fmt.Errorf("cannot encode %q: %w", s, err)
+	}
+	buf.Write(v)
+	buf.WriteByte(0)
+	return nil
+}
+
+func write16(data []byte, v uint16) {
+	data[0] = uint8(v)
+	data[1] = uint8(v >> 8)
+}
+
+func write32(data []byte, v uint32) {
+	write16(data[0:2], uint16(v))
+	write16(data[2:4], uint16(v>>16))
+}
+
+func write64(data []byte, v uint64) {
+	write32(data[0:4], uint32(v))
+	write32(data[4:8], uint32(v>>32))
+}
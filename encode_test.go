@@ -0,0 +1,229 @@
+package crossword
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func TestEncodeAllPuzzles(t *testing.T) {
+	for _, base := range testFiles() {
+		t.Run(base, func(t *testing.T) {
+			file := path.Join(testDataDir, base)
+			orig, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			p, err := Decode(orig)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			puz, err := Encode(p)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			if !bytes.Equal(puz, orig) {
+				t.Errorf("encoded puzzle differs from original")
+			}
+		})
+	}
+}
+
+func TestEncodeModifiedPuzzle(t *testing.T) {
+	p, err := Read(path.Join(testDataDir, "Mar1420.puz"))
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	p.Title = "Modified Title"
+	p.Notepad = "Modified notepad"
+	p.AllClues[0] = "Modified clue"
+	puz, err := Encode(p)
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	q, err := Decode(puz)
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	if q.Title != p.Title {
+		t.Errorf("Title == %q, want %q", q.Title, p.Title)
+	}
+	if q.Notepad != p.Notepad {
+		t.Errorf("Notepad == %q, want %q", q.Notepad, p.Notepad)
+	}
+	if q.Dir[Across].Clues[1] != p.AllClues[0] {
+		t.Errorf("1 %v == %q, want %q", Across, q.Dir[Across].Clues[1], p.AllClues[0])
+	}
+	if q.Solution() != p.Solution() {
+		t.Errorf("solution differs after encoding")
+	}
+}
+
+func TestEncodeNewPuzzle(t *testing.T) {
+	p := &Puzzle{
+		Title:    "Tiny",
+		Width:    3,
+		Height:   3,
+		AllClues: []string{"1A", "1D", "2D", "3D", "4A", "5A"},
+		solution: Grid{
+			[]byte("CAT"),
+			[]byte("ARE"),
+			[]byte("BEE"),
+		},
+	}
+	puz, err := Encode(p)
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	q, err := Decode(puz)
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	if q.Version != defaultVersion {
+		t.Errorf("Version == %q, want %q", q.Version, defaultVersion)
+	}
+	if q.Solution() != p.Solution() {
+		t.Errorf("solution == %q, want %q", q.Solution(), p.Solution())
+	}
+	if q.Dir[Down].Answers[3] != "TEE" {
+		t.Errorf("3 %v == %q, want %q", Down, q.Dir[Down].Answers[3], "TEE")
+	}
+}
+
+func TestFixChecksums(t *testing.T) {
+	orig, err := ioutil.ReadFile(path.Join(testDataDir, "Mar2711.puz"))
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	p, err := Decode(orig)
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	diags, err := p.FixChecksums()
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	if len(diags) != 0 {
+		t.Errorf("FixChecksums changed checksums of valid puzzle: %v", diags)
+	}
+
+	bad := append([]byte{}, orig...)
+	bad[0] ^= 0xFF  // global checksum
+	bad[14] ^= 0xFF // CIB checksum
+	p, diags, err = DecodeWithOptions(bad, DecodeOptions{IgnoreChecksums: true})
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	if len(diags) != 2 {
+		t.Errorf("decoding damaged puzzle produced diagnostics %v", diags)
+	}
+	diags, err = p.FixChecksums()
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	if len(diags) != 2 || diags[0].Section != HeaderSection || diags[1].Section != GlobalSection {
+		t.Errorf("FixChecksums returned %v, want header and global changes", diags)
+	}
+	if !bytes.Equal(p.Header, orig[:headerLength]) {
+		t.Errorf("fixed header % X, want % X", p.Header, orig[:headerLength])
+	}
+	puz, err := Encode(p)
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	if !bytes.Equal(puz, orig) {
+		t.Errorf("encoded puzzle differs from original")
+	}
+}
+
+func TestEncodeVersion2(t *testing.T) {
+	p, err := Read(path.Join(testDataDir, "Mar1420.puz"))
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	clue := "Сноуборд ☃"
+	p.AllClues[0] = clue
+	_, err = Encode(p)
+	if err == nil {
+		t.Errorf("Encode with non-Windows-1252 clue in version %s succeeded, want error", p.Version)
+	}
+	p.Version = "2.0"
+	p.Title = "Café"
+	puz, err := Encode(p)
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	if !bytes.Contains(puz, []byte(clue+"\x00")) {
+		t.Errorf("encoded puzzle does not contain UTF-8 clue %q", clue)
+	}
+	q, err := Decode(puz)
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	if q.Version != "2.0" {
+		t.Errorf("Version == %q, want %q", q.Version, "2.0")
+	}
+	if q.Title != p.Title {
+		t.Errorf("Title == %q, want %q", q.Title, p.Title)
+	}
+	if q.AllClues[0] != clue {
+		t.Errorf("AllClues[0] == %q, want %q", q.AllClues[0], clue)
+	}
+}
+
+func TestEncodePuzzleType(t *testing.T) {
+	orig, err := ioutil.ReadFile(path.Join(testDataDir, "Mar1420.puz"))
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	diagramless := append([]byte{}, orig...)
+	write16(diagramless[48:50], 0x0401)
+	p, _, err := DecodeWithOptions(diagramless, DecodeOptions{IgnoreChecksums: true})
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	if p.Scrambled {
+		t.Errorf("unscrambled diagramless puzzle decoded as scrambled")
+	}
+	_, err = p.FixChecksums()
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	puz, err := Encode(p)
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	if !bytes.Equal(puz[48:52], []byte{0x01, 0x04, 0, 0}) {
+		t.Errorf("encoded puzzle type and scrambled tag == % X, want 01 04 00 00", puz[48:52])
+	}
+	q, err := Decode(puz)
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	if q.Scrambled {
+		t.Errorf("re-encoded diagramless puzzle decoded as scrambled")
+	}
+}
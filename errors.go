@@ -0,0 +1,84 @@
+package crossword
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNoHeader indicates that the data does not contain a PUZ header.
+	ErrNoHeader = fmt.Errorf("puzzle does not contain expected header %q", magic)
+
+	// ErrUnsupportedExtension indicates an unrecognized extension section
+	// encountered while decoding in strict mode.
+	ErrUnsupportedExtension = errors.New("not supported")
+)
+
+type (
+	// ChecksumError indicates that a checksum stored in the puzzle
+	// does not match the contents of the corresponding section.
+	ChecksumError struct {
+		// Section is HeaderSection, GlobalSection, MagicSection, or an extension code.
+		Section string
+		// Got is the checksum computed from the section contents.
+		Got uint64
+		// Want is the checksum stored in the puzzle.
+		Want uint64
+	}
+
+	// TruncatedError indicates that the puzzle ends before the named section is complete.
+	TruncatedError struct {
+		// Section is HeaderSection, SolutionSection, FillSection, StringsSection, or an extension code.
+		Section string
+		// Got is the number of bytes available.
+		Got int
+		// Want is the number of bytes required.
+		// For StringsSection, it is only a lower bound:
+		// one terminating NUL byte for each missing string.
+		Want int
+	}
+
+	// ExtensionError indicates that an extension section could not be decoded.
+	ExtensionError struct {
+		Code string
+		Err  error
+	}
+)
+
+// Sections of a puzzle other than extensions.
+const (
+	HeaderSection   = "header"
+	SolutionSection = "solution"
+	FillSection     = "fill"
+	StringsSection  = "strings"
+	GlobalSection   = "global"
+	MagicSection    = "magic"
+)
+
+func (e *ChecksumError) Error() string {
+	name := e.Section
+	digits := 4
+	switch e.Section {
+	case HeaderSection, GlobalSection:
+	case MagicSection:
+		digits = 16
+	default:
+		name += " extension"
+	}
+	return fmt.Sprintf("%s checksum = %0*X, expected %0*X", name, digits, e.Got, digits, e.Want)
+}
+
+func (e *TruncatedError) Error() string {
+	return fmt.Sprintf("only %d bytes of %s data instead of %d", e.Got, e.Section, e.Want)
+}
+
+func (e *ExtensionError) Error() string {
+	if !isExtensionCode(e.Code) {
+		return fmt.Sprintf("extension %q: %v", e.Code, e.Err)
+	}
+	return fmt.Sprintf("%s extension: %v", e.Code, e.Err)
+}
+
+func (e *ExtensionError) Unwrap() error {
+	return e.Err
+}
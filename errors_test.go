@@ -0,0 +1,153 @@
+package crossword
+
+import (
+	"errors"
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func TestDecodeErrors(t *testing.T) {
+	orig, err := ioutil.ReadFile(path.Join(testDataDir, "Mar1420.puz"))
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	n := len(orig)
+	cases := []struct {
+		name    string
+		modify  func([]byte) []byte
+		opts    DecodeOptions
+		section string
+		check   func(error) bool
+	}{
+		{
+			name:   "no header",
+			modify: func(v []byte) []byte { v[2] = 'a'; return v },
+			check:  func(err error) bool { return errors.Is(err, ErrNoHeader) },
+		},
+		{
+			name:    "short",
+			modify:  func(v []byte) []byte { return v[:40] },
+			section: HeaderSection,
+			check:   isTruncated,
+		},
+		{
+			name:    "short header",
+			modify:  func(v []byte) []byte { return append(make([]byte, 30), v[:headerLength-10]...) },
+			section: HeaderSection,
+			check:   isTruncated,
+		},
+		{
+			name:    "short solution",
+			modify:  func(v []byte) []byte { return v[:headerLength+100] },
+			section: SolutionSection,
+			check:   isTruncated,
+		},
+		{
+			name:    "short fill",
+			modify:  func(v []byte) []byte { return v[:headerLength+15*15+100] },
+			section: FillSection,
+			check:   isTruncated,
+		},
+		{
+			name:    "short strings",
+			modify:  func(v []byte) []byte { return v[:n/2] },
+			section: StringsSection,
+			check:   isTruncated,
+		},
+		{
+			name:    "header checksum",
+			modify:  func(v []byte) []byte { v[14] ^= 0xFF; return v },
+			section: HeaderSection,
+			check:   isChecksumError,
+		},
+		{
+			name:    "global checksum",
+			modify:  func(v []byte) []byte { v[1] ^= 0xFF; return v },
+			section: GlobalSection,
+			check:   isChecksumError,
+		},
+		{
+			name:    "magic checksum",
+			modify:  func(v []byte) []byte { v[20] ^= 0xFF; return v },
+			section: MagicSection,
+			check:   isChecksumError,
+		},
+		{
+			name:    "extension checksum",
+			modify:  func(v []byte) []byte { return append(v, "LTIM\x03\x00\x00\x008,0\x00"...) },
+			section: "LTIM",
+			check:   isChecksumError,
+		},
+		{
+			name:    "truncated extension",
+			modify:  func(v []byte) []byte { return append(v, "LTIM\x30\x00\x00\x008,0\x00"...) },
+			section: "LTIM",
+			check:   isTruncated,
+		},
+		{
+			name:    "malformed extension",
+			modify:  func(v []byte) []byte { return append(v, makeExtension("GEXT", "short")...) },
+			section: "GEXT",
+			check:   isExtensionError,
+		},
+		{
+			name:    "unsupported extension",
+			modify:  func(v []byte) []byte { return append(v, makeExtension("XTRA", "data")...) },
+			opts:    DecodeOptions{Strict: true},
+			section: "XTRA",
+			check: func(err error) bool {
+				return isExtensionError(err) && errors.Is(err, ErrUnsupportedExtension)
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			puz := c.modify(append(make([]byte, 0, n), orig...))
+			_, _, err := DecodeWithOptions(puz, c.opts)
+			if err == nil {
+				t.Errorf("decoding succeeded")
+				return
+			}
+			if !c.check(err) {
+				t.Errorf("unexpected error type %T: %v", errors.Unwrap(err), err)
+				return
+			}
+			if c.section != "" && errorSection(err) != c.section {
+				t.Errorf("error %q has section %q, want %q", err, errorSection(err), c.section)
+			}
+		})
+	}
+}
+
+func isTruncated(err error) bool {
+	var e *TruncatedError
+	return errors.As(err, &e)
+}
+
+func isChecksumError(err error) bool {
+	var e *ChecksumError
+	return errors.As(err, &e)
+}
+
+func isExtensionError(err error) bool {
+	var e *ExtensionError
+	return errors.As(err, &e)
+}
+
+func errorSection(err error) string {
+	var t *TruncatedError
+	if errors.As(err, &t) {
+		return t.Section
+	}
+	var c *ChecksumError
+	if errors.As(err, &c) {
+		return c.Section
+	}
+	var e *ExtensionError
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
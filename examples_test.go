@@ -259,15 +259,15 @@ var PuzzleMar2711 = Puzzle{
 				1:   "BUSY",
 				5:   "REPASTS",
 				12:  "SSR",
-				15:  "ALPE",
+				15:  "ALPINE",
 				16:  "STARSPOT",
-				17:  "PTA",
+				17:  "PINTA",
 				18:  "GADS",
 				19:  "USSENATE",
 				20:  "NAG",
-				21:  "SNOOPG",
+				21:  "SNOOPING",
 				23:  "YSER",
-				24:  "PBERS",
+				24:  "PINBALLERS",
 				26:  "CREAMWARE",
 				28:  "TART",
 				29:  "AIT",
@@ -281,7 +281,7 @@ var PuzzleMar2711 = Puzzle{
 				45:  "ASU",
 				46:  "BASAL",
 				47:  "AMSTELS",
-				50:  "BOT",
+				50:  "BALLOT",
 				51:  "TEARUP",
 				53:  "NEOGENE",
 				55:  "CHEW",
@@ -294,7 +294,7 @@ var PuzzleMar2711 = Puzzle{
 				66:  "THOU",
 				68:  "ATIT",
 				72:  "NOTABADIDEA",
-				76:  "BOU",
+				76:  "BALLOU",
 				77:  "FIONA",
 				79:  "ALERO",
 				80:  "BBGUN",
@@ -308,7 +308,7 @@ var PuzzleMar2711 = Puzzle{
 				97:  "EVIE",
 				98:  "ATARI",
 				101: "UNIQUE",
-				102: "BETS",
+				102: "BALLETS",
 				103: "SATPREP",
 				105: "OUTSAT",
 				107: "HUP",
@@ -321,7 +321,7 @@ var PuzzleMar2711 = Puzzle{
 				119: "KEEPON",
 				123: "UMINN",
 				124: "LEIS",
-				126: "ONEBS",
+				126: "ONEBALLS",
 				128: "ESO",
 				129: "AJAX",
 				131: "ALLABOARD",
@@ -426,7 +426,7 @@ var PuzzleMar2711 = Puzzle{
 			Answers: IndexedStrings{
 				1:   "BAGS",
 				2:   "ULAN",
-				3:   "SPDOCTORS",
+				3:   "SPINDOCTORS",
 				4:   "YESOR",
 				5:   "RTS",
 				6:   "EASYWIN",
@@ -434,20 +434,20 @@ var PuzzleMar2711 = Puzzle{
 				8:   "ASNER",
 				9:   "SPAREMOMENT",
 				10:  "TOT",
-				11:  "STEPTO",
-				12:  "SPNER",
+				11:  "STEPINTO",
+				12:  "SPINNER",
 				13:  "START",
 				14:  "RAGS",
 				16:  "SUGAR",
-				22:  "PEAPPLE",
-				25:  "BADS",
+				22:  "PINEAPPLE",
+				25:  "BALLADS",
 				27:  "MAI",
 				29:  "ATUB",
 				30:  "IRNA",
 				33:  "OSOLE",
 				35:  "MEATWAGON",
 				37:  "RAT",
-				39:  "CABED",
+				39:  "CABALLED",
 				40:  "ASONG",
 				41:  "TUTEE",
 				44:  "ISOLA",
@@ -463,7 +463,7 @@ var PuzzleMar2711 = Puzzle{
 				63:  "HUE",
 				65:  "ETAL",
 				67:  "ODOR",
-				69:  "TBGLOVE",
+				69:  "TBALLGLOVE",
 				70:  "IOU",
 				71:  "TUN",
 				73:  "BAMA",
@@ -471,7 +471,7 @@ var PuzzleMar2711 = Puzzle{
 				75:  "ABIE",
 				77:  "FAT",
 				78:  "IVE",
-				81:  "BASEBTEAM",
+				81:  "BASEBALLTEAM",
 				84:  "ITO",
 				85:  "DRIES",
 				87:  "TARP",
@@ -495,7 +495,7 @@ var PuzzleMar2711 = Puzzle{
 				121: "ONADIME",
 				122: "NEB",
 				125: "EXACTS",
-				127: "BOONS",
+				127: "BALLOONS",
 				129: "ALIVE",
 				130: "JADED",
 				132: "LILTS",
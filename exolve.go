@@ -0,0 +1,174 @@
+package crossword
+
+import (
+	"fmt"
+	"hash/fnv"
+	"html"
+	"io/ioutil"
+	"strings"
+)
+
+// ExolveOptions control how a puzzle is exported for Exolve.
+type ExolveOptions struct {
+	// ScriptURL is the URL of the directory containing the Exolve script
+	// and style sheet, exolve-m.js and exolve-m.css.
+	// If it is empty, they are loaded from the same directory as the page.
+	ScriptURL string
+}
+
+// WriteExolve encodes the puzzle as an Exolve HTML page and writes it to the named file.
+func WriteExolve(file string, p *Puzzle) error {
+	data, err := EncodeExolve(p)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// EncodeExolve returns an HTML page that displays the puzzle with Exolve,
+// which expects the Exolve script and style sheet to be in the same directory.
+// Circled squares are circled, each clue is followed by its length,
+// and the notepad is shown as the preamble.
+// Exolve does not support rebus squares, so they contain only their solution letter.
+func EncodeExolve(p *Puzzle) ([]byte, error) {
+	return EncodeExolveWithOptions(p, ExolveOptions{})
+}
+
+// EncodeExolveWithOptions is like EncodeExolve but loads the Exolve files as specified by opts.
+func EncodeExolveWithOptions(p *Puzzle, opts ExolveOptions) ([]byte, error) {
+	if p.Scrambled {
+		return nil, fmt.Errorf("cannot encode locked puzzle for Exolve")
+	}
+	if !p.hasGridSize(p.solution) {
+		return nil, fmt.Errorf("solution does not match %d×%d puzzle", p.Width, p.Height)
+	}
+	entries := p.clueEntries()
+	if len(entries) != len(p.AllClues) {
+		return nil, fmt.Errorf("puzzle has %d clues for %d entries", len(p.AllClues), len(entries))
+	}
+	var spec strings.Builder
+	spec.WriteString("exolve-begin\n")
+	writeExolveSection(&spec, "id", p.exolveID())
+	writeExolveSection(&spec, "title", html.EscapeString(p.Title))
+	writeExolveSection(&spec, "setter", html.EscapeString(p.Author))
+	copyright := strings.TrimSpace(strings.TrimPrefix(p.Copyright, "©"))
+	writeExolveSection(&spec, "copyright", html.EscapeString(copyright))
+	writeExolveSection(&spec, "width", fmt.Sprint(p.Width))
+	writeExolveSection(&spec, "height", fmt.Sprint(p.Height))
+	grid, digits := p.exolveGrid()
+	if digits {
+		writeExolveSection(&spec, "option", "allow-digits")
+	}
+	if p.Notepad != "" {
+		notepad := strings.Split(strings.ReplaceAll(html.EscapeString(p.Notepad), "\r\n", "\n"), "\n")
+		writeExolveSection(&spec, "preamble", "", notepad...)
+	}
+	writeExolveSection(&spec, "grid", "", grid...)
+	for _, dir := range []Direction{Across, Down} {
+		var clues []string
+		for i, e := range entries {
+			if e.dir != dir {
+				continue
+			}
+			n := len(p.Dir[dir].Words[e.number])
+			clues = append(clues, fmt.Sprintf("%d %s (%d)", e.number, exolveLine(html.EscapeString(p.AllClues[i])), n))
+		}
+		writeExolveSection(&spec, strings.ToLower(dir.String()), "", clues...)
+	}
+	spec.WriteString("exolve-end\n")
+	title := p.Title
+	if title == "" {
+		title = "Crossword"
+	}
+	dir := opts.ScriptURL
+	if dir != "" && !strings.HasSuffix(dir, "/") {
+		dir += "/"
+	}
+	dir = html.EscapeString(dir)
+	var buf strings.Builder
+	fmt.Fprintf(&buf, `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>%s</title>
+<link rel="stylesheet" type="text/css" href="%sexolve-m.css">
+<script src="%sexolve-m.js"></script>
+</head>
+<body>
+<script>
+createExolve(%s);
+</script>
+</body>
+</html>
+`, html.EscapeString(title), dir, dir, jsTemplateLiteral(spec.String()))
+	return []byte(buf.String()), nil
+}
+
+// exolveID returns an identifier for the puzzle, which Exolve uses
+// to save the player's progress in the browser.
+func (p *Puzzle) exolveID() string {
+	h := fnv.New32a()
+	h.Write([]byte(p.Title))
+	h.Write([]byte(p.Solution()))
+	return fmt.Sprintf("crossword-%08x", h.Sum32())
+}
+
+// exolveGrid returns the rows of the grid in Exolve format,
+// and whether any squares contain digits.
+// Squares whose solution is not a letter or digit are shown as unknown.
+func (p *Puzzle) exolveGrid() ([]string, bool) {
+	digits := false
+	rows := make([]string, p.Height)
+	for y := 0; y < p.Height; y++ {
+		cells := make([]string, p.Width)
+		for x := 0; x < p.Width; x++ {
+			c := p.solution[y][x]
+			switch {
+			case p.IsBlack(x, y):
+				cells[x] = "."
+				continue
+			case isScrambledLetter(c):
+			case '0' <= c && c <= '9':
+				digits = true
+			default:
+				c = '0'
+			}
+			cells[x] = string(c)
+			if p.IsCircled(x, y) {
+				cells[x] += "@"
+			}
+		}
+		rows[y] = strings.Join(cells, " ")
+	}
+	return rows, digits
+}
+
+// writeExolveSection writes an Exolve section with its value on the same line
+// and any further lines indented below it.
+func writeExolveSection(buf *strings.Builder, name, value string, lines ...string) {
+	if value == "" && len(lines) == 0 {
+		return
+	}
+	fmt.Fprintf(buf, "  exolve-%s:", name)
+	if value != "" {
+		buf.WriteString(" " + exolveLine(value))
+	}
+	buf.WriteString("\n")
+	for _, line := range lines {
+		fmt.Fprintf(buf, "    %s\n", line)
+	}
+}
+
+// jsTemplateLiteral returns s as a JavaScript template literal
+// that can be included in an HTML script element.
+func jsTemplateLiteral(s string) string {
+	r := strings.NewReplacer("\\", "\\\\", "`", "\\`", "${", "\\${", "</", "<\\/")
+	return "`\n" + r.Replace(s) + "`"
+}
+
+// exolveLine replaces line breaks in s, since each Exolve clue
+// and single-line section must fit on one line.
+func exolveLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,87 @@
+package crossword
+
+import (
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestEncodeExolve(t *testing.T) {
+	text := strings.Replace(sampleText, "Recent", "Recent `</script>` ${x} & more", 1)
+	p, err := DecodeText([]byte(text))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	data, err := EncodeExolve(p)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	page := string(data)
+	for _, want := range []string{
+		"<title>Sample Puzzle</title>",
+		`<link rel="stylesheet" type="text/css" href="exolve-m.css">`,
+		`<script src="exolve-m.js"></script>`,
+		"  exolve-title: Sample Puzzle\n",
+		"  exolve-setter: A. Constructor\n",
+		"  exolve-copyright: 2024 Example Syndicate\n",
+		"  exolve-width: 4\n  exolve-height: 4\n",
+		"  exolve-preamble:\n    First line of the notepad.\n    \n    Last line.\n",
+		"  exolve-grid:\n    C A@ T S\n    R O B E\n    I R O N\n    . N E W\n",
+		"  exolve-across:\n    1 Felines plus fish? (4)\n",
+		"    7 Recent \\`&lt;/script&gt;\\` \\${x} &amp; more (3)\n",
+		"  exolve-down:\n    1 Animal doctor&#39;s charge? (3)\n",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Exolve page does not contain %q", want)
+		}
+	}
+	if strings.Count(page, "</script>") != 2 {
+		t.Errorf("Exolve page contains unescaped end tag")
+	}
+}
+
+func TestEncodeExolveScriptURL(t *testing.T) {
+	p, err := DecodeText([]byte(sampleText))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	data, err := EncodeExolveWithOptions(p, ExolveOptions{ScriptURL: "https://example.com/exolve"})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	want := `<script src="https://example.com/exolve/exolve-m.js"></script>`
+	if !strings.Contains(string(data), want) {
+		t.Errorf("Exolve page does not contain %q", want)
+	}
+}
+
+func TestEncodeExolveAllPuzzles(t *testing.T) {
+	for _, base := range testFiles() {
+		t.Run(base, func(t *testing.T) {
+			p, err := Read(path.Join(testDataDir, base))
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			if p.Scrambled {
+				return
+			}
+			data, err := EncodeExolve(p)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			grid := strings.SplitN(string(data), "  exolve-grid:\n", 2)
+			if len(grid) != 2 {
+				t.Errorf("Exolve page has no grid")
+				return
+			}
+			rows := strings.Split(grid[1], "\n")[:p.Height]
+			for y, row := range rows {
+				if len(strings.Fields(row)) != p.Width {
+					t.Errorf("grid row %d == %q, want %d squares", y, row, p.Width)
+				}
+			}
+		})
+	}
+}
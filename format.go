@@ -0,0 +1,262 @@
+package crossword
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+type (
+	// Format identifies a puzzle file format.
+	Format string
+
+	// FormatCodec describes how to recognize, decode, and encode puzzles in a format.
+	FormatCodec struct {
+		// Extensions are the file name extensions used for the format,
+		// such as ".puz", with the preferred one first.
+		Extensions []string
+		// Detect reports whether data appears to be in this format.
+		// It is nil for formats that cannot be decoded.
+		Detect func(data []byte) bool
+		// Decode decodes a puzzle, or is nil if the format cannot be decoded.
+		Decode func(data []byte) (*Puzzle, error)
+		// Encode encodes a puzzle, or is nil if the format cannot be encoded.
+		Encode func(p *Puzzle) ([]byte, error)
+	}
+)
+
+// Built-in formats.
+const (
+	FormatPUZ    Format = "puz"
+	FormatText   Format = "text"
+	FormatIPUZ   Format = "ipuz"
+	FormatJPZ    Format = "jpz"
+	FormatXD     Format = "xd"
+	FormatExolve Format = "exolve"
+)
+
+var (
+	formatMu sync.RWMutex
+	// Registered formats, in the order in which they are detected.
+	formats []Format
+	codecs  = make(map[Format]FormatCodec)
+)
+
+func init() {
+	RegisterFormat(FormatPUZ, FormatCodec{
+		Extensions: []string{".puz"},
+		Detect:     detectPUZ,
+		Decode:     Decode,
+		Encode:     Encode,
+	})
+	RegisterFormat(FormatText, FormatCodec{
+		Extensions: []string{".txt"},
+		Detect:     detectText,
+		Decode:     DecodeText,
+		Encode:     EncodeText,
+	})
+	RegisterFormat(FormatIPUZ, FormatCodec{
+		Extensions: []string{".ipuz"},
+		Detect:     detectIPUZ,
+		Decode:     DecodeIPUZ,
+		Encode:     EncodeIPUZ,
+	})
+	RegisterFormat(FormatJPZ, FormatCodec{
+		Extensions: []string{".jpz"},
+		Detect:     detectJPZ,
+		Decode:     DecodeJPZ,
+		Encode:     EncodeJPZ,
+	})
+	RegisterFormat(FormatXD, FormatCodec{
+		Extensions: []string{".xd"},
+		Detect:     detectXD,
+		Decode:     DecodeXD,
+		Encode:     EncodeXD,
+	})
+	RegisterFormat(FormatExolve, FormatCodec{
+		Extensions: []string{".html", ".htm"},
+		Encode:     EncodeExolve,
+	})
+}
+
+// RegisterFormat adds a format to those used by Load, DecodeFormat, and EncodeFormat,
+// or replaces the codec of a format that is already registered.
+// Formats are detected in the order in which they were first registered,
+// starting with the built-in ones.
+func RegisterFormat(f Format, c FormatCodec) {
+	formatMu.Lock()
+	defer formatMu.Unlock()
+	if _, ok := codecs[f]; !ok {
+		formats = append(formats, f)
+	}
+	codecs[f] = c
+}
+
+// Formats returns the registered formats in the order in which they are detected.
+func Formats() []Format {
+	formatMu.RLock()
+	defer formatMu.RUnlock()
+	return append([]Format(nil), formats...)
+}
+
+// Codec returns the codec for a registered format.
+func (f Format) Codec() (FormatCodec, bool) {
+	formatMu.RLock()
+	defer formatMu.RUnlock()
+	c, ok := codecs[f]
+	return c, ok
+}
+
+// FormatForFile returns the registered format whose extensions include that of file.
+func FormatForFile(file string) (Format, bool) {
+	ext := strings.ToLower(filepath.Ext(file))
+	if ext == "" {
+		return "", false
+	}
+	formatMu.RLock()
+	defer formatMu.RUnlock()
+	for _, f := range formats {
+		for _, e := range codecs[f].Extensions {
+			if e == ext {
+				return f, true
+			}
+		}
+	}
+	return "", false
+}
+
+// DetectFormat returns the first registered format that recognizes data.
+func DetectFormat(data []byte) (Format, error) {
+	formatMu.RLock()
+	defer formatMu.RUnlock()
+	for _, f := range formats {
+		c := codecs[f]
+		if c.Detect != nil && c.Decode != nil && c.Detect(data) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unrecognized puzzle format")
+}
+
+// DecodeFormat decodes a puzzle in the given format.
+func DecodeFormat(data []byte, f Format) (*Puzzle, error) {
+	c, ok := f.Codec()
+	if !ok {
+		return nil, fmt.Errorf("unknown puzzle format %q", f)
+	}
+	if c.Decode == nil {
+		return nil, fmt.Errorf("cannot decode %s format", f)
+	}
+	return c.Decode(data)
+}
+
+// EncodeFormat encodes a puzzle in the given format.
+func EncodeFormat(p *Puzzle, f Format) ([]byte, error) {
+	c, ok := f.Codec()
+	if !ok {
+		return nil, fmt.Errorf("unknown puzzle format %q", f)
+	}
+	if c.Encode == nil {
+		return nil, fmt.Errorf("cannot encode %s format", f)
+	}
+	return c.Encode(p)
+}
+
+// Load reads all of r, determines its format with DetectFormat, and decodes it.
+func Load(r io.Reader) (*Puzzle, Format, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	f, err := DetectFormat(data)
+	if err != nil {
+		return nil, "", err
+	}
+	p, err := DecodeFormat(data, f)
+	if err != nil {
+		return nil, f, err
+	}
+	return p, f, nil
+}
+
+// LoadFile is like Load but reads the named file.
+func LoadFile(file string) (*Puzzle, Format, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, "", err
+	}
+	p, f, err := Load(bytes.NewReader(data))
+	if err != nil {
+		err = fmt.Errorf("%s: %w", file, err)
+	}
+	return p, f, err
+}
+
+// trimLeading removes a byte order mark and leading white space from data.
+func trimLeading(data []byte) []byte {
+	return bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF")), " \t\r\n")
+}
+
+func detectPUZ(data []byte) bool {
+	return bytes.Index(data, magic) >= 2
+}
+
+func detectText(data []byte) bool {
+	return bytes.HasPrefix(trimLeading(data), []byte("<ACROSS PUZZLE"))
+}
+
+func detectIPUZ(data []byte) bool {
+	data = trimLeading(data)
+	if !bytes.HasPrefix(data, []byte("{")) && !bytes.HasPrefix(data, []byte("ipuz(")) {
+		return false
+	}
+	return bytes.Contains(data, []byte("http://ipuz.org/"))
+}
+
+func detectJPZ(data []byte) bool {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return true
+	}
+	data = trimLeading(data)
+	if !bytes.HasPrefix(data, []byte("<")) {
+		return false
+	}
+	return bytes.Contains(data, []byte("<crossword-compiler")) || bytes.Contains(data, []byte("<rectangular-puzzle"))
+}
+
+// detectXD recognizes xd files by their initial "Key: value" header
+// and the presence of across clues.
+func detectXD(data []byte) bool {
+	lines := strings.Split(strings.ReplaceAll(textString(data), "\r\n", "\n"), "\n")
+	i := skipBlankLines(lines, 0)
+	if i == len(lines) {
+		return false
+	}
+	f := strings.SplitN(lines[i], ":", 2)
+	if len(f) != 2 || !isXDHeaderKey(f[0]) {
+		return false
+	}
+	for _, line := range lines[i:] {
+		if dir, _, _, ok := parseXDClue(strings.TrimSpace(line)); ok && dir == Across {
+			return true
+		}
+	}
+	return false
+}
+
+func isXDHeaderKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if !isLetter(c) && c != '-' && c != ' ' {
+			return false
+		}
+	}
+	return true
+}
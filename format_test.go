@@ -0,0 +1,141 @@
+package crossword
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	base := "Jan0210.puz"
+	p, err := Read(path.Join(testDataDir, base))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for _, f := range []Format{FormatPUZ, FormatText, FormatIPUZ, FormatJPZ, FormatXD} {
+		t.Run(string(f), func(t *testing.T) {
+			data, err := EncodeFormat(p, f)
+			if err != nil {
+				t.Fatalf("%s", err)
+			}
+			q, g, err := Load(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("%s", err)
+			}
+			if g != f {
+				t.Errorf("Load detected format %q, want %q", g, f)
+			}
+			checkSamePuzzle(t, q, p)
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	_, f, err := LoadFile(path.Join(testDataDir, "Jan0210.puz"))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if f != FormatPUZ {
+		t.Errorf("LoadFile detected format %q, want %q", f, FormatPUZ)
+	}
+	_, _, err = LoadFile("format_test.go")
+	if err == nil || !strings.Contains(err.Error(), "format_test.go: unrecognized puzzle format") {
+		t.Errorf("LoadFile returned %v, want unrecognized format error", err)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	cases := []struct {
+		data string
+		want Format
+	}{
+		{sampleText, FormatText},
+		{"\xEF\xBB\xBF\n" + sampleText, FormatText},
+		{sampleIPUZ, FormatIPUZ},
+		{sampleIPUZ[len("ipuz(") : len(sampleIPUZ)-1], FormatIPUZ},
+		{sampleJPZ, FormatJPZ},
+		{sampleXD, FormatXD},
+		{"", ""},
+		{"{\"kind\": [\"something else\"]}", ""},
+		{"Title: not a puzzle\n", ""},
+	}
+	for _, c := range cases {
+		f, err := DetectFormat([]byte(c.data))
+		if f != c.want {
+			t.Errorf("DetectFormat(%.20q) == %q, want %q", c.data, f, c.want)
+		}
+		if (err == nil) != (c.want != "") {
+			t.Errorf("DetectFormat(%.20q) returned error %v", c.data, err)
+		}
+	}
+}
+
+func TestFormatForFile(t *testing.T) {
+	cases := []struct {
+		file string
+		want Format
+	}{
+		{"a/b.puz", FormatPUZ},
+		{"b.PUZ", FormatPUZ},
+		{"b.ipuz", FormatIPUZ},
+		{"b.html", FormatExolve},
+		{"b.pdf", ""},
+		{"b", ""},
+	}
+	for _, c := range cases {
+		f, _ := FormatForFile(c.file)
+		if f != c.want {
+			t.Errorf("FormatForFile(%q) == %q, want %q", c.file, f, c.want)
+		}
+	}
+}
+
+func TestRegisterFormat(t *testing.T) {
+	defer restoreFormats(saveFormats())
+	const custom Format = "custom"
+	RegisterFormat(custom, FormatCodec{
+		Extensions: []string{".custom"},
+		Detect:     func(data []byte) bool { return bytes.HasPrefix(data, []byte("CUSTOM\n")) },
+		Decode: func(data []byte) (*Puzzle, error) {
+			return DecodeText(data[len("CUSTOM\n"):])
+		},
+	})
+	formats := Formats()
+	if formats[len(formats)-1] != custom {
+		t.Errorf("Formats() == %v, want %q last", formats, custom)
+	}
+	p, f, err := Load(strings.NewReader("CUSTOM\n" + sampleText))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if f != custom || p.Title != "Sample Puzzle" {
+		t.Errorf("Load returned format %q and title %q", f, p.Title)
+	}
+	_, err = EncodeFormat(p, custom)
+	if err == nil {
+		t.Errorf("EncodeFormat succeeded for format without encoder")
+	}
+	_, err = DecodeFormat(nil, "unknown")
+	if err == nil {
+		t.Errorf("DecodeFormat succeeded for unknown format")
+	}
+}
+
+// saveFormats returns a copy of the format registry.
+func saveFormats() ([]Format, map[Format]FormatCodec) {
+	formatMu.RLock()
+	defer formatMu.RUnlock()
+	saved := make(map[Format]FormatCodec, len(codecs))
+	for f, c := range codecs {
+		saved[f] = c
+	}
+	return append([]Format(nil), formats...), saved
+}
+
+// restoreFormats replaces the format registry with one saved by saveFormats.
+func restoreFormats(f []Format, c map[Format]FormatCodec) {
+	formatMu.Lock()
+	defer formatMu.Unlock()
+	formats, codecs = f, c
+}
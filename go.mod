@@ -1,6 +1,6 @@
 module github.com/ecc1/crossword
 
-go 1.14
+go 1.16
 
 require (
 	github.com/gotk3/gotk3 v0.6.1
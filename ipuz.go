@@ -0,0 +1,375 @@
+package crossword
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	ipuzVersion = "http://ipuz.org/v2"
+	ipuzKind    = "http://ipuz.org/crossword#1"
+	ipuzBlock   = "#"
+	ipuzEmpty   = "0"
+)
+
+// ipuzFile is the JSON representation of a crossword in ipuz format.
+// Cells and clues can take several forms, so they are decoded generically.
+type ipuzFile struct {
+	Version    string                   `json:"version"`
+	Kind       []string                 `json:"kind"`
+	Title      string                   `json:"title,omitempty"`
+	Author     string                   `json:"author,omitempty"`
+	Copyright  string                   `json:"copyright,omitempty"`
+	Notes      string                   `json:"notes,omitempty"`
+	Block      interface{}              `json:"block,omitempty"`
+	Empty      interface{}              `json:"empty,omitempty"`
+	Dimensions ipuzDimensions           `json:"dimensions"`
+	Puzzle     [][]interface{}          `json:"puzzle"`
+	Solution   [][]interface{}          `json:"solution,omitempty"`
+	Saved      [][]interface{}          `json:"saved,omitempty"`
+	Clues      map[string][]interface{} `json:"clues"`
+}
+
+type ipuzDimensions struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// ipuzCell is the form of a puzzle cell with a style.
+type ipuzCell struct {
+	Cell  interface{}       `json:"cell"`
+	Style map[string]string `json:"style,omitempty"`
+}
+
+// ReadIPUZ reads the named file in ipuz format.
+func ReadIPUZ(file string) (*Puzzle, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	p, err := DecodeIPUZ(data)
+	if err != nil {
+		err = fmt.Errorf("%s: %w", file, err)
+	}
+	return p, err
+}
+
+// DecodeIPUZ decodes a crossword in ipuz format.
+// Squares with the "circle" background shape are circled,
+// squares whose solution has more than one letter become rebus squares
+// (with the first letter in the solution grid),
+// and the saved player state becomes the fill.
+func DecodeIPUZ(data []byte) (*Puzzle, error) {
+	data = bytes.TrimSpace(data)
+	// Allow the JSONP form used by some publishers.
+	if bytes.HasPrefix(data, []byte("ipuz(")) && bytes.HasSuffix(data, []byte(")")) {
+		data = data[len("ipuz(") : len(data)-1]
+	}
+	var f ipuzFile
+	err := json.Unmarshal(data, &f)
+	if err != nil {
+		return nil, fmt.Errorf("malformed ipuz data: %w", err)
+	}
+	if !f.isCrossword() {
+		return nil, fmt.Errorf("ipuz kind %q is not a crossword", f.Kind)
+	}
+	block := ipuzBlock
+	if f.Block != nil {
+		block = ipuzString(f.Block)
+	}
+	empty := ipuzEmpty
+	if f.Empty != nil {
+		empty = ipuzString(f.Empty)
+	}
+	var p Puzzle
+	p.Title = f.Title
+	p.Author = f.Author
+	p.Copyright = f.Copyright
+	p.Notepad = f.Notes
+	p.Width, p.Height = f.Dimensions.Width, f.Dimensions.Height
+	if p.Width <= 0 || p.Height <= 0 {
+		return nil, fmt.Errorf("invalid ipuz dimensions %d×%d", p.Width, p.Height)
+	}
+	for _, g := range []struct {
+		name  string
+		cells [][]interface{}
+	}{{"puzzle", f.Puzzle}, {"solution", f.Solution}, {"saved", f.Saved}} {
+		if g.cells != nil && !ipuzGridSize(g.cells, p.Width, p.Height) {
+			return nil, fmt.Errorf("ipuz %s grid does not match %d×%d puzzle", g.name, p.Width, p.Height)
+		}
+	}
+	if f.Solution == nil {
+		return nil, fmt.Errorf("ipuz puzzle has no solution")
+	}
+	p.solution = p.MakeGrid()
+	for y := 0; y < p.Height; y++ {
+		for x := 0; x < p.Width; x++ {
+			err := p.readIPUZCell(x, y, f.Puzzle, f.Solution[y][x], block)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if f.Saved != nil {
+		p.readIPUZSaved(f.Saved, block, empty)
+	}
+	p.AllClues, err = p.readIPUZClues(f.Clues)
+	if err != nil {
+		return nil, err
+	}
+	p.NumClues = len(p.AllClues)
+	p.setVersion()
+	p.indexClues()
+	return &p, nil
+}
+
+func (f *ipuzFile) isCrossword() bool {
+	for _, k := range f.Kind {
+		if strings.HasPrefix(k, "http://ipuz.org/crossword") {
+			return true
+		}
+	}
+	return false
+}
+
+func ipuzGridSize(g [][]interface{}, w, h int) bool {
+	if len(g) != h {
+		return false
+	}
+	for _, row := range g {
+		if len(row) != w {
+			return false
+		}
+	}
+	return true
+}
+
+// readIPUZCell sets the solution and style of square (x, y).
+func (p *Puzzle) readIPUZCell(x, y int, puzzle [][]interface{}, sol interface{}, block string) error {
+	var cell interface{}
+	if puzzle != nil {
+		cell = puzzle[y][x]
+	}
+	if m, ok := cell.(map[string]interface{}); ok {
+		cell = m["cell"]
+		if style, ok := m["style"].(map[string]interface{}); ok && style["shapebg"] == "circle" {
+			p.SetFlags(x, y, Circled)
+		}
+	}
+	if m, ok := sol.(map[string]interface{}); ok {
+		sol = m["value"]
+	}
+	s := ipuzString(sol)
+	if (puzzle != nil && (cell == nil || ipuzString(cell) == block)) || s == block {
+		p.solution[y][x] = blackSquare
+		return nil
+	}
+	if s == "" {
+		return fmt.Errorf("ipuz solution is missing for square %v", NewPosition(x, y))
+	}
+	p.setRebus(x, y, strings.ToUpper(s))
+	return nil
+}
+
+// readIPUZSaved sets the fill from the saved player state.
+func (p *Puzzle) readIPUZSaved(saved [][]interface{}, block, empty string) {
+	for y := 0; y < p.Height; y++ {
+		for x := 0; x < p.Width; x++ {
+			v := saved[y][x]
+			if m, ok := v.(map[string]interface{}); ok {
+				v = m["value"]
+			}
+			s := strings.ToUpper(ipuzString(v))
+			if p.IsBlack(x, y) || s == "" || s == block || s == strings.ToUpper(empty) {
+				continue
+			}
+			p.SetFillString(x, y, s)
+		}
+	}
+}
+
+// readIPUZClues returns the clues in the order of AllClues.
+// Clues may be given as [number, "clue"] pairs, as objects with "number" and "clue" fields,
+// or as plain strings in increasing order of number.
+func (p *Puzzle) readIPUZClues(lists map[string][]interface{}) ([]string, error) {
+	byNumber := make([]map[int]string, 2)
+	for key, list := range lists {
+		var dir Direction
+		switch strings.SplitN(key, ":", 2)[0] {
+		case "Across":
+			dir = Across
+		case "Down":
+			dir = Down
+		default:
+			return nil, fmt.Errorf("ipuz clue direction %q is not supported", key)
+		}
+		m := make(map[int]string)
+		byNumber[dir] = m
+		var entries []clueEntry
+		for _, e := range p.clueEntries() {
+			if e.dir == dir {
+				entries = append(entries, e)
+			}
+		}
+		for i, c := range list {
+			n, clue, err := ipuzClue(c)
+			if err != nil {
+				return nil, fmt.Errorf("%v clue %d: %w", dir, i+1, err)
+			}
+			if n == 0 && i < len(entries) {
+				n = entries[i].number
+			}
+			m[n] = clue
+		}
+	}
+	var all []string
+	for _, e := range p.clueEntries() {
+		clue, ok := byNumber[e.dir][e.number]
+		if !ok {
+			return nil, fmt.Errorf("no ipuz clue for %d %v", e.number, e.dir)
+		}
+		delete(byNumber[e.dir], e.number)
+		all = append(all, clue)
+	}
+	for dir, m := range byNumber {
+		for n := range m {
+			return nil, fmt.Errorf("ipuz clue for %d %v does not match any word", n, Direction(dir))
+		}
+	}
+	return all, nil
+}
+
+// ipuzClue returns the number and text of a clue, with a number of 0 if it has none.
+func ipuzClue(c interface{}) (int, string, error) {
+	switch v := c.(type) {
+	case string:
+		return 0, v, nil
+	case []interface{}:
+		if len(v) == 2 {
+			n, err := ipuzNumber(v[0])
+			if err != nil {
+				return 0, "", err
+			}
+			return n, ipuzString(v[1]), nil
+		}
+	case map[string]interface{}:
+		n, err := ipuzNumber(v["number"])
+		if err != nil {
+			return 0, "", err
+		}
+		return n, ipuzString(v["clue"]), nil
+	}
+	return 0, "", fmt.Errorf("malformed clue %v", c)
+}
+
+func ipuzNumber(v interface{}) (int, error) {
+	n, err := strconv.Atoi(ipuzString(v))
+	if err != nil {
+		return 0, fmt.Errorf("malformed clue number %v", v)
+	}
+	return n, nil
+}
+
+// ipuzString returns the string form of a JSON string or number.
+func ipuzString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// WriteIPUZ encodes the puzzle in ipuz format and writes it to the named file.
+func WriteIPUZ(file string, p *Puzzle) error {
+	data, err := EncodeIPUZ(p)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// EncodeIPUZ returns the ipuz representation of the puzzle.
+// The player's fill is included as the saved state if any squares are filled in.
+// Square flags other than circles are not represented.
+func EncodeIPUZ(p *Puzzle) ([]byte, error) {
+	if p.Scrambled {
+		return nil, fmt.Errorf("cannot encode locked puzzle as ipuz")
+	}
+	if !p.hasGridSize(p.solution) {
+		return nil, fmt.Errorf("solution does not match %d×%d puzzle", p.Width, p.Height)
+	}
+	entries := p.clueEntries()
+	if len(entries) != len(p.AllClues) {
+		return nil, fmt.Errorf("puzzle has %d clues for %d entries", len(p.AllClues), len(entries))
+	}
+	f := ipuzFile{
+		Version:    ipuzVersion,
+		Kind:       []string{ipuzKind},
+		Title:      p.Title,
+		Author:     p.Author,
+		Copyright:  p.Copyright,
+		Notes:      p.Notepad,
+		Dimensions: ipuzDimensions{Width: p.Width, Height: p.Height},
+		Clues:      make(map[string][]interface{}),
+	}
+	numbers := make(map[Position]int)
+	for i, e := range entries {
+		numbers[e.pos] = e.number
+		key := "Across"
+		if e.dir == Down {
+			key = "Down"
+		}
+		f.Clues[key] = append(f.Clues[key], []interface{}{e.number, p.AllClues[i]})
+	}
+	filled := false
+	for y := 0; y < p.Height; y++ {
+		var puzzle, solution, saved []interface{}
+		for x := 0; x < p.Width; x++ {
+			if p.IsBlack(x, y) {
+				puzzle = append(puzzle, ipuzBlock)
+				solution = append(solution, ipuzBlock)
+				saved = append(saved, ipuzBlock)
+				continue
+			}
+			var cell interface{} = numbers[NewPosition(x, y)]
+			if p.IsCircled(x, y) {
+				cell = ipuzCell{Cell: cell, Style: map[string]string{"shapebg": "circle"}}
+			}
+			puzzle = append(puzzle, cell)
+			solution = append(solution, p.AnswerString(x, y))
+			if p.IsFilled(x, y) {
+				saved = append(saved, p.FillString(x, y))
+				filled = true
+			} else {
+				saved = append(saved, "")
+			}
+		}
+		f.Puzzle = append(f.Puzzle, puzzle)
+		f.Solution = append(f.Solution, solution)
+		f.Saved = append(f.Saved, saved)
+	}
+	if !filled {
+		f.Saved = nil
+	}
+	for _, list := range f.Clues {
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].([]interface{})[0].(int) < list[j].([]interface{})[0].(int)
+		})
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(f)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,153 @@
+package crossword
+
+import (
+	"path"
+	"strings"
+	"testing"
+)
+
+const sampleIPUZ = `ipuz({
+	"version": "http://ipuz.org/v2",
+	"kind": ["http://ipuz.org/crossword#1"],
+	"title": "Sample Puzzle",
+	"author": "A. Constructor",
+	"copyright": "© 2024 Example Syndicate",
+	"notes": "First line of the notepad.\n\nLast line.",
+	"dimensions": {"width": 4, "height": 4},
+	"puzzle": [
+		[1, {"cell": 2, "style": {"shapebg": "circle"}}, 3, 4],
+		[5, 0, 0, 0],
+		[6, 0, 0, 0],
+		["#", 7, 0, 0]
+	],
+	"solution": [
+		[{"value": "CAT"}, "A", "T", "S"],
+		["R", "O", "B", "E"],
+		["I", "R", "O", "N"],
+		["#", "N", "E", "W"]
+	],
+	"saved": [
+		["", "a", "", ""],
+		["R", 0, "", ""],
+		["", "", "", ""],
+		["#", "", "", "X"]
+	],
+	"clues": {
+		"Across": [[1, "Felines plus fish?"], [5, "Bathrobe, e.g."], {"number": 6, "clue": "Press"}, ["7", "Recent"]],
+		"Down": ["Animal doctor's charge?", "Throw", "Attempts", "Stitched"]
+	}
+})`
+
+func TestDecodeIPUZ(t *testing.T) {
+	p, err := DecodeIPUZ([]byte(sampleIPUZ))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if p.Title != "Sample Puzzle" || p.Copyright != "© 2024 Example Syndicate" {
+		t.Errorf("title and copyright == %q, %q", p.Title, p.Copyright)
+	}
+	if p.Notepad != "First line of the notepad.\n\nLast line." {
+		t.Errorf("Notepad == %q", p.Notepad)
+	}
+	if p.Solution() != "CATS\nROBE\nIRON\n.NEW\n" {
+		t.Errorf("solution == %q", p.Solution())
+	}
+	if !p.IsRebus(0, 0) || p.AnswerString(0, 0) != "CAT" {
+		t.Errorf("square (0, 0) == %q, want rebus %q", p.AnswerString(0, 0), "CAT")
+	}
+	if !p.IsCircled(1, 0) || p.IsCircled(2, 0) {
+		t.Errorf("circles are not correct")
+	}
+	if p.FillString(1, 0) != "A" || p.FillString(0, 1) != "R" || p.FillString(3, 3) != "X" || p.IsFilled(1, 1) {
+		t.Errorf("fill == %q", p.FillGrid())
+	}
+	want := map[Direction]map[int]string{
+		Across: {1: "Felines plus fish?", 5: "Bathrobe, e.g.", 6: "Press", 7: "Recent"},
+		Down:   {1: "Animal doctor's charge?", 2: "Throw", 3: "Attempts", 4: "Stitched"},
+	}
+	for dir, clues := range want {
+		for n, clue := range clues {
+			if p.Dir[dir].Clues[n] != clue {
+				t.Errorf("%d %v clue == %q, want %q", n, dir, p.Dir[dir].Clues[n], clue)
+			}
+		}
+	}
+	text, err := DecodeText([]byte(sampleText))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if p.Dir[Across].Answers[1] != text.Dir[Across].Answers[1] {
+		t.Errorf("1 %v == %q, want %q", Across, p.Dir[Across].Answers[1], text.Dir[Across].Answers[1])
+	}
+}
+
+func TestDecodeIPUZEmpty(t *testing.T) {
+	data := strings.Replace(sampleIPUZ, `"dimensions"`, `"empty": "-", "dimensions"`, 1)
+	data = strings.Replace(data, `["R", 0, "", ""]`, `["R", "-", 0, ""]`, 1)
+	p, err := DecodeIPUZ([]byte(data))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if p.IsFilled(1, 1) || p.FillString(2, 1) != "0" {
+		t.Errorf("fill == %q", p.FillGrid())
+	}
+}
+
+func TestEncodeIPUZAllPuzzles(t *testing.T) {
+	for _, base := range testFiles() {
+		t.Run(base, func(t *testing.T) {
+			p, err := Read(path.Join(testDataDir, base))
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			if p.Scrambled {
+				return
+			}
+			data, err := EncodeIPUZ(p)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			q, err := DecodeIPUZ(data)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			checkSamePuzzle(t, q, p)
+			if q.FillGrid() != p.FillGrid() {
+				t.Errorf("fill == %q, want %q", q.FillGrid(), p.FillGrid())
+			}
+		})
+	}
+}
+
+func TestDecodeIPUZErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		edit func(string) string
+		want string
+	}{
+		{"not JSON", func(s string) string { return s[:len(s)/2] }, "malformed ipuz data"},
+		{"wrong kind", func(s string) string { return strings.Replace(s, "crossword#1", "sudoku#1", 1) }, "is not a crossword"},
+		{"short row", func(s string) string { return strings.Replace(s, `"#", "N", "E", "W"`, `"#", "N", "E"`, 1) }, "solution grid does not match"},
+		{"missing solution", func(s string) string { return strings.Replace(s, `"I", "R"`, `"I", ""`, 1) }, "solution is missing"},
+		{"missing clue", func(s string) string { return strings.Replace(s, `, ["7", "Recent"]`, "", 1) }, "no ipuz clue for 7 ACROSS"},
+		{"extra clue", func(s string) string {
+			return strings.Replace(s, `["7", "Recent"]`, `["7", "Recent"], [8, "Extra"]`, 1)
+		}, "does not match any word"},
+		{"bad direction", func(s string) string { return strings.Replace(s, `"Down"`, `"Diagonal"`, 1) }, "not supported"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := DecodeIPUZ([]byte(c.edit(sampleIPUZ)))
+			if err == nil {
+				t.Errorf("DecodeIPUZ succeeded, want error containing %q", c.want)
+				return
+			}
+			if !strings.Contains(err.Error(), c.want) {
+				t.Errorf("DecodeIPUZ returned %q, want error containing %q", err, c.want)
+			}
+		})
+	}
+}
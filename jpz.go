@@ -0,0 +1,533 @@
+package crossword
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+// XML namespaces used in the JPZ format.
+const (
+	jpzAppletSpace = "http://crossword.info/xml/crossword-compiler-applet"
+	jpzPuzzleSpace = "http://crossword.info/xml/rectangular-puzzle"
+	jpzAlphabet    = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+)
+
+// jpzShadingColor is the background color used for shaded squares.
+const jpzShadingColor = "#C0C0C0"
+
+type (
+	// jpzApplet is the root element of a JPZ file.
+	// Some files omit it and use the rectangular-puzzle element as the root.
+	jpzApplet struct {
+		XMLName xml.Name
+		Puzzle  *jpzPuzzle `xml:"rectangular-puzzle"`
+	}
+
+	jpzPuzzle struct {
+		XMLName   xml.Name      `xml:"rectangular-puzzle"`
+		Namespace string        `xml:"xmlns,attr,omitempty"`
+		Alphabet  string        `xml:"alphabet,attr,omitempty"`
+		Metadata  jpzMetadata   `xml:"metadata"`
+		Crossword *jpzCrossword `xml:"crossword"`
+	}
+
+	jpzMetadata struct {
+		Title       string `xml:"title,omitempty"`
+		Creator     string `xml:"creator,omitempty"`
+		Copyright   string `xml:"copyright,omitempty"`
+		Description string `xml:"description,omitempty"`
+	}
+
+	jpzCrossword struct {
+		Grid  jpzGrid    `xml:"grid"`
+		Words []jpzWord  `xml:"word"`
+		Clues []jpzClues `xml:"clues"`
+	}
+
+	jpzGrid struct {
+		Width  int       `xml:"width,attr"`
+		Height int       `xml:"height,attr"`
+		Cells  []jpzCell `xml:"cell"`
+	}
+
+	// jpzCell is a square of the grid, with 1-based coordinates.
+	jpzCell struct {
+		X               int    `xml:"x,attr"`
+		Y               int    `xml:"y,attr"`
+		Type            string `xml:"type,attr,omitempty"`
+		Solution        string `xml:"solution,attr,omitempty"`
+		Number          string `xml:"number,attr,omitempty"`
+		SolveState      string `xml:"solve-state,attr,omitempty"`
+		BackgroundShape string `xml:"background-shape,attr,omitempty"`
+		BackgroundColor string `xml:"background-color,attr,omitempty"`
+	}
+
+	// jpzWord lists the squares of a word, either as ranges in its attributes
+	// or as individual cells.
+	jpzWord struct {
+		ID    string     `xml:"id,attr"`
+		X     string     `xml:"x,attr,omitempty"`
+		Y     string     `xml:"y,attr,omitempty"`
+		Cells []jpzRange `xml:"cells"`
+	}
+
+	jpzRange struct {
+		X string `xml:"x,attr"`
+		Y string `xml:"y,attr"`
+	}
+
+	jpzClues struct {
+		Ordering string    `xml:"ordering,attr,omitempty"`
+		Title    jpzTitle  `xml:"title"`
+		Clues    []jpzClue `xml:"clue"`
+	}
+
+	// jpzTitle holds the heading of a list of clues, which may contain markup.
+	jpzTitle struct {
+		Text string `xml:",innerxml"`
+	}
+
+	// jpzClue holds a clue, which may contain markup.
+	jpzClue struct {
+		Word   string `xml:"word,attr"`
+		Number string `xml:"number,attr"`
+		Format string `xml:"format,attr,omitempty"`
+		Text   string `xml:",innerxml"`
+	}
+)
+
+// ReadJPZ reads the named file in JPZ format.
+func ReadJPZ(file string) (*Puzzle, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	p, err := DecodeJPZ(data)
+	if err != nil {
+		err = fmt.Errorf("%s: %w", file, err)
+	}
+	return p, err
+}
+
+// DecodeJPZ decodes a puzzle in the JPZ format used by Crossword Compiler,
+// either as plain XML or as a ZIP archive containing it.
+// Squares with a circular background shape are circled, and those with
+// a background color other than white are shaded.
+// Bars between squares are not supported, so a puzzle that uses them to delimit words
+// is rejected when its words do not match those implied by its black squares.
+// Likewise, clue numbers must match the standard numbering of the grid.
+func DecodeJPZ(data []byte) (*Puzzle, error) {
+	data, err := unzipJPZ(data)
+	if err != nil {
+		return nil, err
+	}
+	jp, err := unmarshalJPZ(data)
+	if err != nil {
+		return nil, err
+	}
+	if jp.Crossword == nil {
+		return nil, fmt.Errorf("JPZ puzzle is not a crossword")
+	}
+	var p Puzzle
+	p.Title = jp.Metadata.Title
+	p.Author = jp.Metadata.Creator
+	p.Copyright = jp.Metadata.Copyright
+	p.Notepad = jp.Metadata.Description
+	g := jp.Crossword.Grid
+	p.Width, p.Height = g.Width, g.Height
+	if p.Width <= 0 || p.Width > maxGridSize || p.Height <= 0 || p.Height > maxGridSize {
+		return nil, fmt.Errorf("invalid JPZ grid size %d×%d", p.Width, p.Height)
+	}
+	err = p.readJPZGrid(g.Cells)
+	if err != nil {
+		return nil, err
+	}
+	err = p.readJPZClues(jp.Crossword)
+	if err != nil {
+		return nil, err
+	}
+	p.setVersion()
+	return &p, nil
+}
+
+// unzipJPZ returns the XML contents of a JPZ file, extracting it from a ZIP archive if necessary.
+func unzipJPZ(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return data, nil
+	}
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range z.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+	}
+	return nil, fmt.Errorf("JPZ archive is empty")
+}
+
+func unmarshalJPZ(data []byte) (*jpzPuzzle, error) {
+	var root jpzApplet
+	err := newJPZDecoder(data).Decode(&root)
+	if err != nil {
+		return nil, fmt.Errorf("malformed JPZ data: %w", err)
+	}
+	if root.XMLName.Local != "rectangular-puzzle" {
+		if root.Puzzle == nil {
+			return nil, fmt.Errorf("JPZ data has no rectangular-puzzle element")
+		}
+		return root.Puzzle, nil
+	}
+	var jp jpzPuzzle
+	err = newJPZDecoder(data).Decode(&jp)
+	if err != nil {
+		return nil, fmt.Errorf("malformed JPZ data: %w", err)
+	}
+	return &jp, nil
+}
+
+// newJPZDecoder returns an XML decoder that accepts the character sets
+// declared by JPZ files, such as ISO-8859-1.
+func newJPZDecoder(data []byte) *xml.Decoder {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.CharsetReader = func(label string, r io.Reader) (io.Reader, error) {
+		e, err := ianaindex.IANA.Encoding(label)
+		if err != nil {
+			return nil, err
+		}
+		if e == nil {
+			return nil, fmt.Errorf("unsupported character set %q", label)
+		}
+		return e.NewDecoder().Reader(r), nil
+	}
+	return d
+}
+
+// readJPZGrid sets the solution, fill, and circles from the cells of a JPZ grid.
+// Squares without a cell element are black.
+func (p *Puzzle) readJPZGrid(cells []jpzCell) error {
+	p.solution = p.MakeGrid()
+	for y := 0; y < p.Height; y++ {
+		for x := 0; x < p.Width; x++ {
+			p.solution[y][x] = blackSquare
+		}
+	}
+	var fill []jpzCell
+	for _, c := range cells {
+		x, y := c.X-1, c.Y-1
+		if x < 0 || x >= p.Width || y < 0 || y >= p.Height {
+			return fmt.Errorf("JPZ cell (%d, %d) is outside the %d×%d grid", c.X, c.Y, p.Width, p.Height)
+		}
+		switch c.Type {
+		case "block", "void":
+			continue
+		case "", "letter":
+		default:
+			return fmt.Errorf("JPZ cell type %q is not supported", c.Type)
+		}
+		if c.Solution == "" {
+			return fmt.Errorf("JPZ solution is missing for square %v", NewPosition(x, y))
+		}
+		p.setRebus(x, y, strings.ToUpper(c.Solution))
+		var flags SquareFlags
+		if c.BackgroundShape == "circle" {
+			flags |= Circled
+		}
+		if isShadingColor(c.BackgroundColor) {
+			flags |= Shaded
+		}
+		p.SetFlags(x, y, flags)
+		if c.SolveState != "" {
+			fill = append(fill, c)
+		}
+	}
+	// Set the fill after the solution, since black squares cannot be filled.
+	for _, c := range fill {
+		p.SetFillString(c.X-1, c.Y-1, strings.ToUpper(c.SolveState))
+	}
+	return nil
+}
+
+// readJPZClues sets the clues of the puzzle from the JPZ clue lists,
+// checking that each clue's word and number agree with the grid.
+func (p *Puzzle) readJPZClues(cw *jpzCrossword) error {
+	words := make(map[string]Word)
+	for _, w := range cw.Words {
+		word, err := w.positions()
+		if err != nil {
+			return err
+		}
+		words[w.ID] = word
+	}
+	entries := p.clueEntries()
+	index := make(map[clueEntry]int)
+	for i, e := range entries {
+		index[clueEntry{dir: e.dir, pos: e.pos}] = i
+	}
+	clues := make([]string, len(entries))
+	seen := make([]bool, len(entries))
+	for _, list := range cw.Clues {
+		title := strings.ToLower(jpzPlainText(list.Title.Text))
+		for _, c := range list.Clues {
+			word, ok := words[c.Word]
+			if !ok || len(word) == 0 {
+				return fmt.Errorf("JPZ clue %s refers to missing word %q", c.Number, c.Word)
+			}
+			dir := Across
+			switch {
+			case strings.Contains(title, "across"):
+			case strings.Contains(title, "down"):
+				dir = Down
+			case len(word) > 1 && word[0].X == word[1].X:
+				dir = Down
+			}
+			i, ok := index[clueEntry{dir: dir, pos: word[0]}]
+			if !ok {
+				return fmt.Errorf("JPZ word %q does not begin a %v entry", c.Word, dir)
+			}
+			if seen[i] {
+				return fmt.Errorf("duplicate JPZ clue for %d %v", entries[i].number, dir)
+			}
+			if c.Number != strconv.Itoa(entries[i].number) {
+				return fmt.Errorf("JPZ clue number %s does not match %d %v", c.Number, entries[i].number, dir)
+			}
+			seen[i] = true
+			clues[i] = jpzPlainText(c.Text)
+		}
+	}
+	for i, e := range entries {
+		if !seen[i] {
+			return fmt.Errorf("no JPZ clue for %d %v", e.number, e.dir)
+		}
+	}
+	p.AllClues = clues
+	p.NumClues = len(clues)
+	p.indexClues()
+	// Check that the words defined in the file agree with the clue indexing.
+	for _, list := range cw.Clues {
+		for _, c := range list.Clues {
+			word := words[c.Word]
+			n, _ := strconv.Atoi(c.Number)
+			if !p.hasWord(n, word) {
+				return fmt.Errorf("JPZ word %q for clue %d does not match the grid", c.Word, n)
+			}
+		}
+	}
+	return nil
+}
+
+// hasWord reports whether word is the across or down word numbered n.
+func (p *Puzzle) hasWord(n int, word Word) bool {
+	for _, d := range p.Dir {
+		w := d.Words[n]
+		if len(w) != len(word) || len(w) == 0 {
+			continue
+		}
+		same := true
+		for i := range w {
+			if w[i] != word[i] {
+				same = false
+				break
+			}
+		}
+		if same {
+			return true
+		}
+	}
+	return false
+}
+
+// positions returns the 0-based positions of the squares of a JPZ word.
+func (w jpzWord) positions() (Word, error) {
+	ranges := w.Cells
+	if w.X != "" || w.Y != "" {
+		ranges = append([]jpzRange{{X: w.X, Y: w.Y}}, ranges...)
+	}
+	var word Word
+	for _, r := range ranges {
+		x0, x1, err := jpzRangeBounds(r.X)
+		if err != nil {
+			return nil, fmt.Errorf("JPZ word %q: %w", w.ID, err)
+		}
+		y0, y1, err := jpzRangeBounds(r.Y)
+		if err != nil {
+			return nil, fmt.Errorf("JPZ word %q: %w", w.ID, err)
+		}
+		for y := y0; y <= y1; y++ {
+			for x := x0; x <= x1; x++ {
+				word = append(word, NewPosition(x-1, y-1))
+			}
+		}
+	}
+	return word, nil
+}
+
+// isShadingColor reports whether a JPZ background color shades a square,
+// which is the case for any color other than white or transparent.
+func isShadingColor(color string) bool {
+	switch strings.ToLower(strings.TrimSpace(color)) {
+	case "", "none", "transparent", "white", "#fff", "#ffffff":
+		return false
+	}
+	return true
+}
+
+// jpzRangeBounds parses a coordinate or range of the form "3" or "3-7".
+func jpzRangeBounds(s string) (int, int, error) {
+	v := strings.SplitN(s, "-", 2)
+	lo, err := strconv.Atoi(strings.TrimSpace(v[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("malformed coordinate %q", s)
+	}
+	if len(v) == 1 {
+		return lo, lo, nil
+	}
+	hi, err := strconv.Atoi(strings.TrimSpace(v[1]))
+	if err != nil || hi < lo {
+		return 0, 0, fmt.Errorf("malformed coordinate range %q", s)
+	}
+	return lo, hi, nil
+}
+
+// jpzPlainText removes markup from the contents of an XML element
+// and replaces character references.
+func jpzPlainText(s string) string {
+	var sb strings.Builder
+	for {
+		i := strings.IndexByte(s, '<')
+		if i == -1 {
+			sb.WriteString(s)
+			break
+		}
+		sb.WriteString(s[:i])
+		j := strings.IndexByte(s[i:], '>')
+		if j == -1 {
+			break
+		}
+		s = s[i+j+1:]
+	}
+	return strings.TrimSpace(html.UnescapeString(sb.String()))
+}
+
+// jpzEscape escapes s for use as the contents of an XML element.
+func jpzEscape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// WriteJPZ encodes the puzzle in JPZ format and writes it to the named file.
+func WriteJPZ(file string, p *Puzzle) error {
+	data, err := EncodeJPZ(p)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// EncodeJPZ returns the JPZ representation of the puzzle as plain XML.
+// Circled squares are encoded with a circular background shape,
+// shaded squares with a gray background color,
+// and the player's fill is encoded as the solve state of each square.
+func EncodeJPZ(p *Puzzle) ([]byte, error) {
+	if p.Scrambled {
+		return nil, fmt.Errorf("cannot encode locked puzzle as JPZ")
+	}
+	if !p.hasGridSize(p.solution) {
+		return nil, fmt.Errorf("solution does not match %d×%d puzzle", p.Width, p.Height)
+	}
+	entries := p.clueEntries()
+	if len(entries) != len(p.AllClues) {
+		return nil, fmt.Errorf("puzzle has %d clues for %d entries", len(p.AllClues), len(entries))
+	}
+	cw := &jpzCrossword{Grid: jpzGrid{Width: p.Width, Height: p.Height}}
+	for y := 0; y < p.Height; y++ {
+		for x := 0; x < p.Width; x++ {
+			c := jpzCell{X: x + 1, Y: y + 1}
+			if p.IsBlack(x, y) {
+				c.Type = "block"
+				cw.Grid.Cells = append(cw.Grid.Cells, c)
+				continue
+			}
+			c.Solution = p.AnswerString(x, y)
+			if n := p.SquareNumber(x, y); n != 0 {
+				c.Number = strconv.Itoa(n)
+			}
+			if p.IsFilled(x, y) {
+				c.SolveState = p.FillString(x, y)
+			}
+			if p.IsCircled(x, y) {
+				c.BackgroundShape = "circle"
+			}
+			if p.IsShaded(x, y) {
+				c.BackgroundColor = jpzShadingColor
+			}
+			cw.Grid.Cells = append(cw.Grid.Cells, c)
+		}
+	}
+	lists := []jpzClues{
+		{Ordering: "normal", Title: jpzTitle{Text: "<b>Across</b>"}},
+		{Ordering: "normal", Title: jpzTitle{Text: "<b>Down</b>"}},
+	}
+	for i, e := range entries {
+		id := strconv.Itoa(i + 1)
+		word := p.Dir[e.dir].Words[e.number]
+		last := word[len(word)-1]
+		w := jpzWord{ID: id}
+		if e.dir == Across {
+			w.X = fmt.Sprintf("%d-%d", e.pos.X+1, last.X+1)
+			w.Y = strconv.Itoa(e.pos.Y + 1)
+		} else {
+			w.X = strconv.Itoa(e.pos.X + 1)
+			w.Y = fmt.Sprintf("%d-%d", e.pos.Y+1, last.Y+1)
+		}
+		cw.Words = append(cw.Words, w)
+		lists[e.dir].Clues = append(lists[e.dir].Clues, jpzClue{
+			Word:   id,
+			Number: strconv.Itoa(e.number),
+			Format: strconv.Itoa(len(word)),
+			Text:   jpzEscape(p.AllClues[i]),
+		})
+	}
+	cw.Clues = lists
+	root := jpzApplet{
+		XMLName: xml.Name{Space: jpzAppletSpace, Local: "crossword-compiler-applet"},
+		Puzzle: &jpzPuzzle{
+			Namespace: jpzPuzzleSpace,
+			Alphabet:  jpzAlphabet,
+			Metadata: jpzMetadata{
+				Title:       p.Title,
+				Creator:     p.Author,
+				Copyright:   p.Copyright,
+				Description: p.Notepad,
+			},
+			Crossword: cw,
+		},
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	err := enc.Encode(root)
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,215 @@
+package crossword
+
+import (
+	"archive/zip"
+	"bytes"
+	"path"
+	"strings"
+	"testing"
+)
+
+const sampleJPZ = `<?xml version="1.0" encoding="UTF-8"?>
+<crossword-compiler-applet xmlns="http://crossword.info/xml/crossword-compiler-applet">
+<rectangular-puzzle xmlns="http://crossword.info/xml/rectangular-puzzle" alphabet="ABCDEFGHIJKLMNOPQRSTUVWXYZ">
+<metadata>
+	<title>Sample Puzzle</title>
+	<creator>A. Constructor</creator>
+	<copyright>© 2024 Example Syndicate</copyright>
+	<description>First line of the notepad.</description>
+</metadata>
+<crossword>
+	<grid width="4" height="4">
+		<grid-look numbering-scheme="normal"/>
+		<cell x="1" y="1" solution="CAT" number="1"/>
+		<cell x="2" y="1" solution="A" number="2" background-shape="circle"/>
+		<cell x="3" y="1" solution="T" number="3" background-color="#C0C0C0"/>
+		<cell x="4" y="1" solution="S" number="4" background-color="#FFFFFF"/>
+		<cell x="1" y="2" solution="R" number="5" solve-state="r"/>
+		<cell x="2" y="2" solution="O"/>
+		<cell x="3" y="2" solution="B"/>
+		<cell x="4" y="2" solution="E"/>
+		<cell x="1" y="3" solution="I" number="6"/>
+		<cell x="2" y="3" solution="R"/>
+		<cell x="3" y="3" solution="O"/>
+		<cell x="4" y="3" solution="N"/>
+		<cell x="1" y="4" type="block"/>
+		<cell x="2" y="4" solution="N" number="7"/>
+		<cell x="3" y="4" solution="E"/>
+		<cell x="4" y="4" solution="W"/>
+	</grid>
+	<word id="1" x="1-4" y="1"/>
+	<word id="2" x="1-4" y="2"/>
+	<word id="3" x="1-4" y="3"/>
+	<word id="4" x="2-4" y="4"/>
+	<word id="5"><cells x="1" y="1"/><cells x="1" y="2"/><cells x="1" y="3"/></word>
+	<word id="6" x="2" y="1-4"/>
+	<word id="7" x="3" y="1-4"/>
+	<word id="8" x="4" y="1-4"/>
+	<clues ordering="normal">
+		<title><b>Across</b></title>
+		<clue word="1" number="1" format="4">Felines plus <i>fish</i>?</clue>
+		<clue word="2" number="5" format="4">Bathrobe, e.g.</clue>
+		<clue word="3" number="6" format="4">Press</clue>
+		<clue word="4" number="7" format="3">Recent</clue>
+	</clues>
+	<clues ordering="normal">
+		<title><b>Down</b></title>
+		<clue word="5" number="1" format="3">Animal doctor&apos;s charge?</clue>
+		<clue word="6" number="2" format="4">Throw</clue>
+		<clue word="7" number="3" format="4">Attempts &amp; tries</clue>
+		<clue word="8" number="4" format="4">Stitched</clue>
+	</clues>
+</crossword>
+</rectangular-puzzle>
+</crossword-compiler-applet>
+`
+
+func TestDecodeJPZ(t *testing.T) {
+	p, err := DecodeJPZ([]byte(sampleJPZ))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	checkSampleJPZ(t, p)
+}
+
+func TestDecodeJPZZipped(t *testing.T) {
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	w, err := z.Create("sample.xml")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	_, _ = w.Write([]byte(sampleJPZ))
+	err = z.Close()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	p, err := DecodeJPZ(buf.Bytes())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	checkSampleJPZ(t, p)
+}
+
+func checkSampleJPZ(t *testing.T, p *Puzzle) {
+	t.Helper()
+	if p.Title != "Sample Puzzle" || p.Author != "A. Constructor" || p.Copyright != "© 2024 Example Syndicate" {
+		t.Errorf("title, author, copyright == %q, %q, %q", p.Title, p.Author, p.Copyright)
+	}
+	if p.Notepad != "First line of the notepad." {
+		t.Errorf("Notepad == %q", p.Notepad)
+	}
+	if p.Solution() != "CATS\nROBE\nIRON\n.NEW\n" {
+		t.Errorf("solution == %q", p.Solution())
+	}
+	if !p.IsRebus(0, 0) || p.AnswerString(0, 0) != "CAT" {
+		t.Errorf("square (0, 0) == %q, want rebus %q", p.AnswerString(0, 0), "CAT")
+	}
+	if !p.IsCircled(1, 0) || p.IsCircled(2, 0) || p.IsCircled(3, 0) {
+		t.Errorf("circles are not correct")
+	}
+	if p.IsShaded(1, 0) || !p.IsShaded(2, 0) || p.IsShaded(3, 0) {
+		t.Errorf("shading is not correct")
+	}
+	if p.FillString(0, 1) != "R" || p.IsFilled(1, 1) {
+		t.Errorf("fill == %q", p.FillGrid())
+	}
+	if p.Dir[Across].Clues[1] != "Felines plus fish?" {
+		t.Errorf("1 %v clue == %q", Across, p.Dir[Across].Clues[1])
+	}
+	if p.Dir[Down].Clues[1] != "Animal doctor's charge?" || p.Dir[Down].Clues[3] != "Attempts & tries" {
+		t.Errorf("1 and 3 %v clues == %q, %q", Down, p.Dir[Down].Clues[1], p.Dir[Down].Clues[3])
+	}
+	if p.Dir[Down].Answers[1] != "CATRI" {
+		t.Errorf("1 %v == %q, want %q", Down, p.Dir[Down].Answers[1], "CATRI")
+	}
+}
+
+func TestEncodeJPZ(t *testing.T) {
+	p, err := DecodeJPZ([]byte(sampleJPZ))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	data, err := EncodeJPZ(p)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for _, want := range []string{`number="1" format="4"`, `number="1" format="3"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("EncodeJPZ output does not contain %s", want)
+		}
+	}
+	q, err := DecodeJPZ(data)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for y := 0; y < p.Height; y++ {
+		for x := 0; x < p.Width; x++ {
+			if q.Flags(x, y) != p.Flags(x, y) {
+				t.Errorf("flags at %v == %#x, want %#x", NewPosition(x, y), q.Flags(x, y), p.Flags(x, y))
+			}
+		}
+	}
+}
+
+func TestEncodeJPZAllPuzzles(t *testing.T) {
+	for _, base := range testFiles() {
+		t.Run(base, func(t *testing.T) {
+			p, err := Read(path.Join(testDataDir, base))
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			if p.Scrambled {
+				return
+			}
+			data, err := EncodeJPZ(p)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			q, err := DecodeJPZ(data)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			checkSamePuzzle(t, q, p)
+			if q.FillGrid() != p.FillGrid() {
+				t.Errorf("fill == %q, want %q", q.FillGrid(), p.FillGrid())
+			}
+		})
+	}
+}
+
+func TestDecodeJPZErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		edit func(string) string
+		want string
+	}{
+		{"not XML", func(s string) string { return s[:len(s)/2] }, "malformed JPZ data"},
+		{"missing solution", func(s string) string { return strings.Replace(s, ` solution="W"`, "", 1) }, "solution is missing"},
+		{"huge grid", func(s string) string {
+			return strings.Replace(s, `width="4" height="4"`, `width="100000" height="100000"`, 1)
+		}, "invalid JPZ grid size"},
+		{"outside grid", func(s string) string { return strings.Replace(s, `x="4" y="4"`, `x="5" y="4"`, 1) }, "outside the 4×4 grid"},
+		{"missing word", func(s string) string { return strings.Replace(s, `<word id="8" x="4" y="1-4"/>`, "", 1) }, "missing word"},
+		{"wrong number", func(s string) string { return strings.Replace(s, `number="7" format="3"`, `number="8" format="3"`, 1) }, "number 8 does not match 7 ACROSS"},
+		{"barred word", func(s string) string { return strings.Replace(s, `x="2-4" y="4"`, `x="2-3" y="4"`, 1) }, "does not match the grid"},
+		{"missing clue", func(s string) string {
+			return strings.Replace(s, `<clue word="3" number="6" format="4">Press</clue>`, "", 1)
+		}, "no JPZ clue for 6 ACROSS"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := DecodeJPZ([]byte(c.edit(sampleJPZ)))
+			if err == nil {
+				t.Errorf("DecodeJPZ succeeded, want error containing %q", c.want)
+				return
+			}
+			if !strings.Contains(err.Error(), c.want) {
+				t.Errorf("DecodeJPZ returned %q, want error containing %q", err, c.want)
+			}
+		})
+	}
+}
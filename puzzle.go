@@ -1,19 +1,26 @@
 /*
-Package crossword provides functions to read crossword puzzles
-in the AcrossLite PUZ file format and render them as PDF files.
+Package crossword provides functions to read and write crossword puzzles
+in the AcrossLite PUZ and text formats, ipuz, Crossword Compiler JPZ, and xd,
+and render them as PDF files or Exolve web pages.
 */
 package crossword
 
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"math/bits"
+	"strconv"
 	"strings"
+	"time"
 
+	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
 )
 
 type (
@@ -35,13 +42,31 @@ type (
 		AllClues  []string
 		Scrambled bool
 
+		// Solving time from the LTIM extension, or nil if there is none.
+		Timer *Timer
+
 		// Clue information indexed by direction.
 		Dir []Clue
 
 		// Height * Width grids.
-		numbers  Grid
+		numbers  NumberGrid
 		solution Grid
-		circles  Grid
+		fill     Grid
+		flags    Grid // GEXT extension
+
+		// Rebus squares: nonzero entries are 1 + the key of the rebus in rebusTable.
+		rebus      Grid
+		rebusTable map[int]string
+
+		// Player's rebus entries from the RUSR extension, or "" for ordinary squares.
+		userRebus [][]string
+
+		// Extension sections that are not otherwise interpreted,
+		// in the order they appeared in the file.
+		Extensions []Extension
+
+		// Codes of all extension sections in the order they appeared in the file.
+		sections []string
 	}
 
 	Direction int
@@ -62,11 +87,14 @@ type (
 		Words IndexedWords
 		// Start[y][x] is clue number for the word that passes through square (x, y).
 		// May be zero for uncrossed words in unusual puzzles.
-		Start Grid
+		Start NumberGrid
 	}
 
 	Grid [][]uint8
 
+	// NumberGrid holds a clue number for each square.
+	NumberGrid [][]int
+
 	Position struct {
 		X int
 		Y int
@@ -83,16 +111,53 @@ type (
 		Scrambled uint16
 		Magic     uint64
 	}
+
+	// Timer records the time spent solving a puzzle.
+	Timer struct {
+		// Elapsed time in seconds.
+		Elapsed int
+		// Stopped is true if the timer was not running when the puzzle was saved.
+		Stopped bool
+	}
+
+	// Extension is an extension section with an unrecognized code,
+	// which is preserved so that it can be encoded again unchanged.
+	Extension struct {
+		Code string
+		Data []byte
+	}
 )
 
 const (
 	Across Direction = 0
 	Down   Direction = 1
 
-	blackSquare   = '.'
-	circledSquare = 0x80
+	blackSquare = '.'
+	emptySquare = '-'
+
+	// maxGridSize is the largest width or height that the PUZ format can represent.
+	maxGridSize = 255
+)
+
+// SquareFlags is a bitmask of the per-square flags in the GEXT extension.
+// Bits other than those named here are preserved but not interpreted.
+type SquareFlags uint8
+
+const (
+	// Shaded is not used by AcrossLite; it records squares with a shaded background
+	// in formats that support them, such as JPZ.
+	Shaded              SquareFlags = 0x08
+	PreviouslyIncorrect SquareFlags = 0x10
+	MarkedIncorrect     SquareFlags = 0x20
+	Revealed            SquareFlags = 0x40
+	Circled             SquareFlags = 0x80
 )
 
+// Has reports whether all the flags in g are set in f.
+func (f SquareFlags) Has(g SquareFlags) bool {
+	return f&g == g
+}
+
 func (dir Direction) String() string {
 	switch dir {
 	case Across:
@@ -119,6 +184,14 @@ func NewGrid(w, h int) Grid {
 	return g
 }
 
+func NewNumberGrid(w, h int) NumberGrid {
+	g := make(NumberGrid, h)
+	for i := range g {
+		g[i] = make([]int, w)
+	}
+	return g
+}
+
 func (g Grid) Write(w io.Writer) {
 	f := bufio.NewWriter(w)
 	for y := 0; y < len(g); y++ {
@@ -140,6 +213,15 @@ func (g Grid) Contents() []byte {
 	return buf.Bytes()
 }
 
+// bytes returns the contents of the grid in row-major order, without newlines.
+func (g Grid) bytes() []byte {
+	var buf bytes.Buffer
+	for _, row := range g {
+		buf.Write(row)
+	}
+	return buf.Bytes()
+}
+
 func (p *Puzzle) IsBlack(x, y int) bool {
 	if x < 0 || x >= p.Width || y < 0 || y >= p.Height {
 		return true
@@ -147,17 +229,123 @@ func (p *Puzzle) IsBlack(x, y int) bool {
 	return p.solution[y][x] == blackSquare
 }
 
-func (p *Puzzle) IsCircled(x, y int) bool {
-	if len(p.circles) == 0 {
-		return false
+// Flags returns the GEXT flags for square (x, y).
+func (p *Puzzle) Flags(x, y int) SquareFlags {
+	if len(p.flags) == 0 {
+		return 0
+	}
+	return SquareFlags(p.flags[y][x])
+}
+
+// SetFlags replaces the GEXT flags for square (x, y).
+func (p *Puzzle) SetFlags(x, y int, f SquareFlags) {
+	if len(p.flags) == 0 {
+		if f == 0 {
+			return
+		}
+		p.flags = p.MakeGrid()
 	}
-	return p.circles[y][x] == circledSquare
+	p.flags[y][x] = uint8(f)
+}
+
+func (p *Puzzle) IsCircled(x, y int) bool {
+	return p.Flags(x, y).Has(Circled)
+}
+
+// IsShaded reports whether square (x, y) has a shaded background.
+func (p *Puzzle) IsShaded(x, y int) bool {
+	return p.Flags(x, y).Has(Shaded)
 }
 
+// IsRevealed reports whether the answer for square (x, y) was given to the player.
+func (p *Puzzle) IsRevealed(x, y int) bool {
+	return p.Flags(x, y).Has(Revealed)
+}
+
+// WasIncorrect reports whether square (x, y) was previously marked incorrect.
+func (p *Puzzle) WasIncorrect(x, y int) bool {
+	return p.Flags(x, y).Has(PreviouslyIncorrect)
+}
+
+// IsMarkedIncorrect reports whether square (x, y) is currently marked incorrect.
+func (p *Puzzle) IsMarkedIncorrect(x, y int) bool {
+	return p.Flags(x, y).Has(MarkedIncorrect)
+}
+
+// Answer returns the solution letter for square (x, y).
+// For a rebus square, this is the first letter of the rebus.
 func (p *Puzzle) Answer(x, y int) byte {
 	return p.solution[y][x]
 }
 
+// AnswerString returns the complete solution for square (x, y),
+// which has more than one letter if it is a rebus square.
+func (p *Puzzle) AnswerString(x, y int) string {
+	if s, ok := p.rebusString(x, y); ok {
+		return s
+	}
+	return string(p.solution[y][x])
+}
+
+// IsRebus reports whether square (x, y) has a multi-letter solution.
+func (p *Puzzle) IsRebus(x, y int) bool {
+	_, ok := p.rebusString(x, y)
+	return ok
+}
+
+func (p *Puzzle) rebusString(x, y int) (string, bool) {
+	if len(p.rebus) == 0 || p.rebus[y][x] == 0 {
+		return "", false
+	}
+	s, ok := p.rebusTable[int(p.rebus[y][x])-1]
+	return s, ok
+}
+
+// setRebus sets the solution for square (x, y) to s,
+// adding it to the rebus table if it has more than one letter.
+func (p *Puzzle) setRebus(x, y int, s string) {
+	if len(s) == 0 {
+		return
+	}
+	if len(s) == 1 {
+		p.solution[y][x] = s[0]
+		if len(p.rebus) != 0 {
+			p.rebus[y][x] = 0
+		}
+		return
+	}
+	p.addRebus(x, y, s, s[0])
+}
+
+// addRebus sets the solution for square (x, y) to the rebus s,
+// with c as the single-letter solution used by programs without rebus support.
+func (p *Puzzle) addRebus(x, y int, s string, c byte) {
+	p.solution[y][x] = c
+	if len(p.rebus) == 0 {
+		p.rebus = p.MakeGrid()
+	}
+	if p.rebusTable == nil {
+		p.rebusTable = make(map[int]string)
+	}
+	key := -1
+	for k, v := range p.rebusTable {
+		if v == s {
+			key = k
+			break
+		}
+	}
+	if key == -1 {
+		// Use the smallest unused key.
+		for key = 0; ; key++ {
+			if _, used := p.rebusTable[key]; !used {
+				break
+			}
+		}
+		p.rebusTable[key] = s
+	}
+	p.rebus[y][x] = uint8(key + 1)
+}
+
 func (p *Puzzle) Solution() string {
 	return p.solution.String()
 }
@@ -166,6 +354,91 @@ func (p *Puzzle) SolutionBytes() []byte {
 	return p.solution.Contents()
 }
 
+// Fill returns the player's entry in square (x, y), or '-' if it is empty.
+func (p *Puzzle) Fill(x, y int) byte {
+	if len(p.fill) == 0 {
+		if p.IsBlack(x, y) {
+			return blackSquare
+		}
+		return emptySquare
+	}
+	return p.fill[y][x]
+}
+
+// FillString returns the player's complete entry in square (x, y),
+// which has more than one letter if it is a rebus entry.
+func (p *Puzzle) FillString(x, y int) string {
+	if len(p.userRebus) != 0 && p.userRebus[y][x] != "" {
+		return p.userRebus[y][x]
+	}
+	return string(p.Fill(x, y))
+}
+
+// SetFill records c as the player's entry in square (x, y).
+// Black squares are not changed.
+func (p *Puzzle) SetFill(x, y int, c byte) {
+	if p.IsBlack(x, y) {
+		return
+	}
+	if len(p.fill) == 0 {
+		p.fill = p.emptyFill()
+	}
+	p.fill[y][x] = c
+	if len(p.userRebus) != 0 {
+		p.userRebus[y][x] = ""
+	}
+}
+
+// SetFillString records s as the player's entry in square (x, y).
+// If s has more than one letter, it is stored as a rebus entry
+// and its first letter is used in the fill grid.
+func (p *Puzzle) SetFillString(x, y int, s string) {
+	switch len(s) {
+	case 0:
+		p.ClearFill(x, y)
+		return
+	case 1:
+		p.SetFill(x, y, s[0])
+		return
+	}
+	if p.IsBlack(x, y) {
+		return
+	}
+	p.SetFill(x, y, s[0])
+	if len(p.userRebus) == 0 {
+		p.userRebus = p.makeStringGrid()
+	}
+	p.userRebus[y][x] = s
+}
+
+// ClearFill removes the player's entry in square (x, y).
+func (p *Puzzle) ClearFill(x, y int) {
+	p.SetFill(x, y, emptySquare)
+}
+
+// IsFilled reports whether the player has made an entry in square (x, y).
+func (p *Puzzle) IsFilled(x, y int) bool {
+	if p.IsBlack(x, y) {
+		return false
+	}
+	return p.Fill(x, y) != emptySquare
+}
+
+// IsCorrect reports whether the player's entry in square (x, y) matches the solution.
+// The result is not meaningful for a scrambled puzzle.
+func (p *Puzzle) IsCorrect(x, y int) bool {
+	return p.IsFilled(x, y) && p.FillString(x, y) == p.AnswerString(x, y)
+}
+
+// FillGrid returns the player's entries as a string with one line per row,
+// in the same format as Solution.
+func (p *Puzzle) FillGrid() string {
+	if len(p.fill) == 0 {
+		return p.emptyFill().String()
+	}
+	return p.fill.String()
+}
+
 // PositionNumber(pos) is the number for the square at position pos, or 0.
 func (p *Puzzle) PositionNumber(pos Position) int {
 	return p.SquareNumber(pos.X, pos.Y)
@@ -173,7 +446,7 @@ func (p *Puzzle) PositionNumber(pos Position) int {
 
 // SquareNumber(x, y) is the number for square (x, y), or 0.
 func (p *Puzzle) SquareNumber(x, y int) int {
-	return int(p.numbers[y][x])
+	return p.numbers[y][x]
 }
 
 func Read(file string) (*Puzzle, error) {
@@ -188,51 +461,93 @@ func Read(file string) (*Puzzle, error) {
 	return p, err
 }
 
+// ReadFS is like Read but reads the named file from fsys.
+func ReadFS(fsys fs.FS, name string) (*Puzzle, error) {
+	puz, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	p, err := Decode(puz)
+	if err != nil {
+		err = fmt.Errorf("%s: %w", name, err)
+	}
+	return p, err
+}
+
+// ReadWithOptions is like Read but decodes the puzzle using DecodeWithOptions.
+func ReadWithOptions(file string, opts DecodeOptions) (*Puzzle, []Diagnostic, error) {
+	puz, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	p, diags, err := DecodeWithOptions(puz, opts)
+	if err != nil {
+		err = fmt.Errorf("%s: %w", file, err)
+	}
+	return p, diags, err
+}
+
 func Decode(puz []byte) (*Puzzle, error) {
+	p, _, err := DecodeWithOptions(puz, DecodeOptions{})
+	return p, err
+}
+
+// DecodeReader reads all of r and decodes it as a puzzle.
+func DecodeReader(r io.Reader) (*Puzzle, error) {
+	puz, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(puz)
+}
+
+// DecodeWithOptions decodes a puzzle as specified by opts.
+// It also returns diagnostics for any problems that were tolerated,
+// even if decoding ultimately fails.
+func DecodeWithOptions(puz []byte, opts DecodeOptions) (*Puzzle, []Diagnostic, error) {
+	d := &decoder{opts: opts}
+	p, err := d.decode(puz)
+	if err != nil {
+		return nil, d.diagnostics, err
+	}
+	return p, d.diagnostics, nil
+}
+
+func (d *decoder) decode(puz []byte) (*Puzzle, error) {
 	var p Puzzle
 	var err error
-	puz, err = p.readHeader(puz)
+	puz, err = p.readHeader(puz, d)
 	if err != nil {
 		return nil, err
 	}
 
 	w, h := p.Width, p.Height
 	grids := puz // remember start of solution and fill grids for global checksum
-	p.solution, puz, err = p.readGrid(puz)
+	p.solution, puz, err = p.readGrid(SolutionSection, puz)
 	if err != nil {
 		return nil, fmt.Errorf("malformed solution section in %d×%d puzzle: %w", w, h, err)
 	}
-	_, puz, err = p.readGrid(puz)
+	p.fill, puz, err = p.readGrid(FillSection, puz)
 	if err != nil {
 		return nil, fmt.Errorf("malformed fill section in %d×%d puzzle: %w", w, h, err)
 	}
 
-	p.Title, puz = readString(puz)
-	p.Author, puz = readString(puz)
-	p.Copyright, puz = readString(puz)
-
-	p.numbers = p.MakeGrid()
-	p.AllClues = make([]string, p.NumClues)
-	for i := range p.AllClues {
-		p.AllClues[i], puz = readString(puz)
-	}
-	p.indexClues()
-	numAcross := len(p.Dir[Across].Numbers)
-	numDown := len(p.Dir[Down].Numbers)
-	n := numAcross + numDown
-	if n != p.NumClues {
-		return nil, fmt.Errorf("%d %v + %d %v clues were indexed instead of %d", numAcross, Across, numDown, Down, p.NumClues)
+	// The title, author, copyright, clues, and notepad.
+	v, puz, err := p.readStrings(puz, 3+p.NumClues+1)
+	if err != nil {
+		return nil, fmt.Errorf("malformed strings section in %d×%d puzzle: %w", w, h, err)
 	}
+	p.Title, p.Author, p.Copyright = v[0], v[1], v[2]
+	p.AllClues = v[3 : 3+p.NumClues]
+	p.Notepad = v[3+p.NumClues]
 
-	p.Notepad, puz = readString(puz)
-
-	err = p.validateChecksums(grids)
+	err = p.validateChecksums(grids, d)
 	if err != nil {
 		return nil, err
 	}
 
 	for {
-		puz, err = p.readExtension(puz)
+		puz, err = p.readExtension(puz, d)
 		if err != nil {
 			return nil, fmt.Errorf("malformed extension section in %d×%d puzzle: %w", w, h, err)
 		}
@@ -240,6 +555,18 @@ func Decode(puz []byte) (*Puzzle, error) {
 			break
 		}
 	}
+	err = p.validateRebus()
+	if err != nil {
+		return nil, fmt.Errorf("malformed rebus in %d×%d puzzle: %w", w, h, &ExtensionError{Code: "GRBS", Err: err})
+	}
+
+	// The header checksum may have been ignored, so check
+	// that there is a clue for each word before indexing them.
+	if n := len(p.clueEntries()); n != p.NumClues {
+		return nil, fmt.Errorf("%d×%d puzzle has %d clues for %d entries", w, h, p.NumClues, n)
+	}
+	// Index the clues after reading the extensions, so that answers include rebus entries.
+	p.indexClues()
 
 	return &p, nil
 }
@@ -248,22 +575,27 @@ const headerLength = 52
 
 var magic = []byte("ACROSS&DOWN\x00")
 
-func (p *Puzzle) readHeader(v []byte) ([]byte, error) {
+func (p *Puzzle) readHeader(v []byte, d *decoder) ([]byte, error) {
 	if len(v) < headerLength {
-		return nil, fmt.Errorf("puzzle is only %d bytes long", len(v))
+		return nil, &TruncatedError{Section: HeaderSection, Got: len(v), Want: headerLength}
 	}
 	// Header starts 2 bytes before the "ACROSS&DOWN" string.
 	i := bytes.Index(v, magic) - 2
 	if i < 0 {
-		return nil, fmt.Errorf("puzzle does not contain expected header %q", magic)
+		return nil, ErrNoHeader
+	}
+	if len(v)-i < headerLength {
+		return nil, &TruncatedError{Section: HeaderSection, Got: len(v) - i, Want: headerLength}
+	}
+	if i > 0 {
+		d.report(Diagnostic{Kind: LeadingGarbage, Length: i})
 	}
 	headerEnd := i + headerLength
 	p.Header = v[i:headerEnd]
 	h := p.Header
-	check := read16(h[14:16])
-	calc := p.headerChecksum()
-	if calc != check {
-		return nil, fmt.Errorf("header checksum = %04X, expected %04X", calc, check)
+	err := d.checkChecksum(HeaderSection, uint64(p.headerChecksum()), uint64(read16(h[14:16])))
+	if err != nil {
+		return nil, err
 	}
 	p.Checksum = Checksums{
 		Global:    read16(h[0:2]),
@@ -274,7 +606,7 @@ func (p *Puzzle) readHeader(v []byte) ([]byte, error) {
 	p.Width = int(h[44])
 	p.Height = int(h[45])
 	p.NumClues = int(read16(h[46:48]))
-	p.Scrambled = read16(h[49:51]) != 0
+	p.Scrambled = read16(h[50:52]) != 0
 	return v[headerEnd:], nil
 }
 
@@ -290,22 +622,50 @@ func read64(data []byte) uint64 {
 	return uint64(read32(data[4:8]))<<32 | uint64(read32(data[0:4]))
 }
 
-func readString(v []byte) (string, []byte) {
-	var buf strings.Builder
-	for i, b := range v {
-		if b == 0 {
-			return makeString(buf.String()), v[i+1:]
+// readStrings reads n NUL-terminated strings.
+func (p *Puzzle) readStrings(v []byte, n int) ([]string, []byte, error) {
+	strs := make([]string, n)
+	rest := v
+	for i := range strs {
+		var ok bool
+		strs[i], rest, ok = p.readString(rest)
+		if !ok {
+			return nil, nil, &TruncatedError{Section: StringsSection, Got: len(v), Want: len(v) + n - i}
 		}
-		buf.WriteByte(b)
 	}
-	return makeString(buf.String()), nil
+	return strs, rest, nil
 }
 
-func makeString(orig string) string {
-	s, _ := charmap.Windows1252.NewDecoder().String(orig)
+// readString reads a NUL-terminated string.
+// It reports false if the terminator is missing.
+func (p *Puzzle) readString(v []byte) (string, []byte, bool) {
+	i := bytes.IndexByte(v, 0)
+	if i == -1 {
+		return "", nil, false
+	}
+	return p.makeString(string(v[:i])), v[i+1:], true
+}
+
+// textEncoding returns the encoding used for strings in the puzzle:
+// UTF-8 for version 2.0 and later, Windows-1252 for earlier versions.
+func (p *Puzzle) textEncoding() encoding.Encoding {
+	if p.Version >= "2.0" {
+		return unicode.UTF8
+	}
+	return charmap.Windows1252
+}
+
+func (p *Puzzle) makeString(orig string) string {
+	s, _ := p.textEncoding().NewDecoder().String(orig)
 	return s
 }
 
+// puzzleBytes returns s in the puzzle's text encoding.
+func (p *Puzzle) puzzleBytes(s string) []byte {
+	v, _ := p.textEncoding().NewEncoder().Bytes([]byte(s))
+	return v
+}
+
 func PuzzleBytes(s string) []byte {
 	v, _ := charmap.Windows1252.NewEncoder().Bytes([]byte(s))
 	return v
@@ -320,9 +680,21 @@ func (p *Puzzle) MakeGrid() Grid {
 	return NewGrid(p.Width, p.Height)
 }
 
-func (p *Puzzle) readGrid(v []byte) (Grid, []byte, error) {
+func (p *Puzzle) makeNumberGrid() NumberGrid {
+	return NewNumberGrid(p.Width, p.Height)
+}
+
+func (p *Puzzle) makeStringGrid() [][]string {
+	g := make([][]string, p.Height)
+	for i := range g {
+		g[i] = make([]string, p.Width)
+	}
+	return g
+}
+
+func (p *Puzzle) readGrid(section string, v []byte) (Grid, []byte, error) {
 	if len(v) < p.Height*p.Width {
-		return nil, nil, fmt.Errorf("only %d bytes of grid data instead of %d", len(v), p.Height*p.Width)
+		return nil, nil, &TruncatedError{Section: section, Got: len(v), Want: p.Height * p.Width}
 	}
 	g := p.MakeGrid()
 	for i := range g {
@@ -332,44 +704,172 @@ func (p *Puzzle) readGrid(v []byte) (Grid, []byte, error) {
 	return g, v, nil
 }
 
-func (p *Puzzle) readExtension(v []byte) ([]byte, error) {
+func (p *Puzzle) readExtension(v []byte, d *decoder) ([]byte, error) {
+	if len(v) == 0 {
+		return nil, nil
+	}
 	if len(v) < 8 {
+		// Too short to be an extension section.
+		d.report(Diagnostic{Kind: TrailingGarbage, Length: len(v)})
 		return nil, nil
 	}
 	code := string(v[0:4])
+	if !isExtensionCode(code) {
+		if d.opts.AllowTrailingGarbage {
+			d.report(Diagnostic{Kind: TrailingGarbage, Length: len(v)})
+			return nil, nil
+		}
+		return nil, &ExtensionError{Code: code, Err: errors.New("malformed code")}
+	}
 	count := int(read16(v[4:6]))
 	check := read16(v[6:8])
-	v = v[8:]
-	if len(v) < count+1 || count < 0 {
-		return nil, fmt.Errorf("only %d bytes of %s extension data instead of %d", len(v), code, count+1)
+	if len(v[8:]) < count+1 {
+		if d.opts.AllowTrailingGarbage {
+			d.report(Diagnostic{Kind: TrailingGarbage, Section: code, Length: len(v)})
+			return nil, nil
+		}
+		return nil, &TruncatedError{Section: code, Got: len(v[8:]), Want: count + 1}
 	}
+	v = v[8:]
 	data := v[:count]
-	calc := checksum(data, 0)
-	if calc != check {
-		return nil, fmt.Errorf("%s extension checksum = %04X, expected %04X", code, calc, check)
+	err := d.checkChecksum(code, uint64(checksum(data, 0)), uint64(check))
+	if err != nil {
+		return nil, err
+	}
+	p.sections = append(p.sections, code)
+	err = p.parseExtension(code, data, d)
+	if err != nil {
+		return nil, &ExtensionError{Code: code, Err: err}
 	}
+	return v[count+1:], nil
+}
+
+func (p *Puzzle) parseExtension(code string, data []byte, d *decoder) error {
+	var err error
 	switch code {
 	case "GEXT":
-		if len(data) != p.Height*p.Width {
-			return nil, fmt.Errorf("%s extension contains %d bytes of data instead of %d", code, len(data), p.Height*p.Width)
-		}
-		var err error
-		p.circles, _, err = p.readGrid(data)
-		if err != nil {
-			return nil, fmt.Errorf("%s extension: %w", code, err)
-		}
+		p.flags, err = p.readExtensionGrid(code, data)
 	case "GRBS":
+		p.rebus, err = p.readExtensionGrid(code, data)
 	case "LTIM":
+		p.Timer, err = readTimer(data)
 	case "RTBL":
+		p.rebusTable, err = readRebusTable(data)
+		for k, v := range p.rebusTable {
+			p.rebusTable[k] = p.makeString(v)
+		}
 	case "RUSR":
+		p.userRebus, err = p.readUserRebus(data)
 	default:
-		return nil, fmt.Errorf("unsupported %s extension", code)
+		if d.opts.Strict {
+			return ErrUnsupportedExtension
+		}
+		e := Extension{Code: code, Data: make([]byte, len(data))}
+		copy(e.Data, data)
+		p.Extensions = append(p.Extensions, e)
 	}
-	return v[count+1:], nil
+	return err
+}
+
+func (p *Puzzle) readExtensionGrid(code string, data []byte) (Grid, error) {
+	if len(data) != p.Height*p.Width {
+		return nil, fmt.Errorf("contains %d bytes of data instead of %d", len(data), p.Height*p.Width)
+	}
+	g, _, err := p.readGrid(code, data)
+	return g, err
+}
+
+// readRebusTable parses the contents of an RTBL extension,
+// which consists of entries of the form "NN:REBUS;".
+// The entries are returned without decoding from the puzzle's text encoding.
+func readRebusTable(data []byte) (map[int]string, error) {
+	table := make(map[int]string)
+	for _, entry := range strings.Split(string(data), ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		i := strings.IndexByte(entry, ':')
+		if i == -1 {
+			return nil, fmt.Errorf("malformed rebus table entry %q", entry)
+		}
+		key, err := strconv.Atoi(strings.TrimSpace(entry[:i]))
+		if err != nil || key < 0 || key > 254 {
+			return nil, fmt.Errorf("malformed rebus table key in %q", entry)
+		}
+		table[key] = entry[i+1:]
+	}
+	return table, nil
+}
+
+// readUserRebus parses the contents of an RUSR extension,
+// which consists of a NUL-terminated string for each square in row-major order.
+func (p *Puzzle) readUserRebus(data []byte) ([][]string, error) {
+	g := p.makeStringGrid()
+	for y := 0; y < p.Height; y++ {
+		for x := 0; x < p.Width; x++ {
+			i := bytes.IndexByte(data, 0)
+			if i == -1 {
+				return nil, fmt.Errorf("only %d of %d strings present", y*p.Width+x, p.Height*p.Width)
+			}
+			g[y][x] = p.makeString(string(data[:i]))
+			data = data[i+1:]
+		}
+	}
+	if len(data) != 0 {
+		return nil, fmt.Errorf("%d bytes of extra data", len(data))
+	}
+	return g, nil
+}
+
+// readTimer parses the contents of an LTIM extension,
+// which has the form "ELAPSED,STOPPED".
+func readTimer(data []byte) (*Timer, error) {
+	fields := strings.Split(string(data), ",")
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("malformed timer %q", data)
+	}
+	elapsed, err := strconv.Atoi(fields[0])
+	if err != nil || elapsed < 0 {
+		return nil, fmt.Errorf("malformed elapsed time in timer %q", data)
+	}
+	var stopped bool
+	switch fields[1] {
+	case "0":
+		stopped = false
+	case "1":
+		stopped = true
+	default:
+		return nil, fmt.Errorf("malformed state in timer %q", data)
+	}
+	return &Timer{Elapsed: elapsed, Stopped: stopped}, nil
+}
+
+func (t Timer) String() string {
+	return (time.Duration(t.Elapsed) * time.Second).String()
+}
+
+// validateRebus checks that every rebus square has an entry in the rebus table.
+func (p *Puzzle) validateRebus() error {
+	if len(p.rebus) == 0 {
+		return nil
+	}
+	for y := 0; y < p.Height; y++ {
+		for x := 0; x < p.Width; x++ {
+			n := p.rebus[y][x]
+			if n == 0 {
+				continue
+			}
+			if _, ok := p.rebusTable[int(n)-1]; !ok {
+				return fmt.Errorf("square %v refers to missing rebus table entry %d", NewPosition(x, y), n-1)
+			}
+		}
+	}
+	return nil
 }
 
 // indexClues determines clue numbers and indexes their positions, numbers, clues, and answers.
 func (p *Puzzle) indexClues() {
+	p.numbers = p.makeNumberGrid()
 	p.Dir = make([]Clue, 2)
 	for i := range p.Dir {
 		d := &p.Dir[i]
@@ -378,32 +878,52 @@ func (p *Puzzle) indexClues() {
 		d.Answers = make(IndexedStrings)
 		d.Positions = make(IndexedPositions)
 		d.Words = make(IndexedWords)
-		d.Start = p.MakeGrid()
+		d.Start = p.makeNumberGrid()
 	}
-	c := 0 // clue index
-	n := 1 // square number
+	for i, e := range p.clueEntries() {
+		x, y := e.pos.X, e.pos.Y
+		p.readAnswer(e.number, e.dir, x, y, p.AllClues[i])
+		p.numbers[y][x] = e.number
+	}
+}
+
+// clueEntry identifies the word for a clue.
+type clueEntry struct {
+	number int
+	dir    Direction
+	pos    Position
+}
+
+// clueEntries returns the words of the puzzle in the order of their clues in AllClues.
+func (p *Puzzle) clueEntries() []clueEntry {
+	var v []clueEntry
+	n := 1
 	for y := 0; y < p.Height; y++ {
 		for x := 0; x < p.Width; x++ {
 			if p.IsBlack(x, y) {
 				continue
 			}
 			numbered := false
-			if p.IsBlack(x-1, y) && !p.IsBlack(x+1, y) {
-				p.readAnswer(n, Across, x, y, p.AllClues[c])
-				numbered = true
-				c++
-			}
-			if p.IsBlack(x, y-1) && !p.IsBlack(x, y+1) {
-				p.readAnswer(n, Down, x, y, p.AllClues[c])
-				numbered = true
-				c++
+			for _, dir := range []Direction{Across, Down} {
+				if p.startsWord(dir, x, y) {
+					v = append(v, clueEntry{number: n, dir: dir, pos: NewPosition(x, y)})
+					numbered = true
+				}
 			}
 			if numbered {
-				p.numbers[y][x] = uint8(n)
 				n++
 			}
 		}
 	}
+	return v
+}
+
+// startsWord reports whether the non-black square (x, y) begins a word in direction dir.
+func (p *Puzzle) startsWord(dir Direction, x, y int) bool {
+	if dir == Across {
+		return p.IsBlack(x-1, y) && !p.IsBlack(x+1, y)
+	}
+	return p.IsBlack(x, y-1) && !p.IsBlack(x, y+1)
 }
 
 func (p *Puzzle) readAnswer(n int, dir Direction, x, y int, clue string) {
@@ -417,8 +937,8 @@ func (p *Puzzle) readAnswer(n int, dir Direction, x, y int, clue string) {
 				break
 			}
 			word = append(word, NewPosition(i, y))
-			sb.WriteByte(p.solution[y][i])
-			d.Start[y][i] = uint8(n)
+			sb.WriteString(p.AnswerString(i, y))
+			d.Start[y][i] = n
 		}
 	case Down:
 		for j := y; j < p.Height; j++ {
@@ -426,8 +946,8 @@ func (p *Puzzle) readAnswer(n int, dir Direction, x, y int, clue string) {
 				break
 			}
 			word = append(word, NewPosition(x, j))
-			sb.WriteByte(p.solution[j][x])
-			d.Start[j][x] = uint8(n)
+			sb.WriteString(p.AnswerString(x, j))
+			d.Start[j][x] = n
 		}
 	}
 	answer := sb.String()
@@ -439,6 +959,15 @@ func (p *Puzzle) readAnswer(n int, dir Direction, x, y int, clue string) {
 	d.Words[n] = word
 }
 
+// wordAnswer returns the solution of the squares in w, including any rebus entries.
+func (p *Puzzle) wordAnswer(w Word) string {
+	var sb strings.Builder
+	for _, pos := range w {
+		sb.WriteString(p.AnswerString(pos.X, pos.Y))
+	}
+	return sb.String()
+}
+
 func checksum(data []byte, c uint16) uint16 {
 	for _, b := range data {
 		c = bits.RotateLeft16(c, -1) + uint16(b)
@@ -448,21 +977,34 @@ func checksum(data []byte, c uint16) uint16 {
 
 var mask = []byte("ICHEATED")
 
-func (p *Puzzle) validateChecksums(grids []byte) error {
-	n := p.Height * p.Width
+func (p *Puzzle) validateChecksums(grids []byte, d *decoder) error {
+	cib := p.headerChecksum()
 
-	c := p.headerChecksum()
-	c = checksum(grids[:2*n], c)
-	c = p.textChecksum(c)
-	if c != p.Checksum.Global {
-		return fmt.Errorf("global checksum = %04X, expected %04X", c, p.Checksum.Global)
+	c := p.globalChecksum(cib, grids)
+	err := d.checkChecksum(GlobalSection, uint64(c), uint64(p.Checksum.Global))
+	if err != nil {
+		return err
 	}
 
+	m := p.magicChecksum(cib, grids)
+	return d.checkChecksum(MagicSection, m, p.Checksum.Magic)
+}
+
+// globalChecksum computes the checksum over the header, the solution and fill grids, and the text.
+func (p *Puzzle) globalChecksum(cib uint16, grids []byte) uint16 {
+	n := p.Height * p.Width
+	c := checksum(grids[:2*n], cib)
+	return p.textChecksum(c)
+}
+
+// magicChecksum computes the "ICHEATED"-masked checksum of the puzzle components.
+func (p *Puzzle) magicChecksum(cib uint16, grids []byte) uint64 {
+	n := p.Height * p.Width
 	sums := []uint16{
 		p.textChecksum(0),
 		checksum(grids[n:2*n], 0),
 		checksum(grids[:n], 0),
-		p.headerChecksum(),
+		cib,
 	}
 	m := uint64(0)
 	for i, c := range sums {
@@ -470,37 +1012,39 @@ func (p *Puzzle) validateChecksums(grids []byte) error {
 		m |= uint64(mask[7-i]^uint8(c>>8)) << 32
 		m |= uint64(mask[3-i]^uint8(c)) << 0
 	}
-	if m != p.Checksum.Magic {
-		return fmt.Errorf("magic checksum = %016X, expected %016X", c, p.Checksum.Magic)
-	}
-
-	return nil
+	return m
 }
 
 func (p *Puzzle) headerChecksum() uint16 {
-	return checksum(p.Header[44:52], 0)
+	return cibChecksum(p.Header)
+}
+
+// cibChecksum computes the checksum of the CIB (width, height, number of clues, and flags)
+// in a puzzle header.
+func cibChecksum(h []byte) uint16 {
+	return checksum(h[44:52], 0)
 }
 
 func (p *Puzzle) textChecksum(c uint16) uint16 {
-	c = zStringChecksum(p.Title, c)
-	c = zStringChecksum(p.Author, c)
-	c = zStringChecksum(p.Copyright, c)
+	c = p.zStringChecksum(p.Title, c)
+	c = p.zStringChecksum(p.Author, c)
+	c = p.zStringChecksum(p.Copyright, c)
 	for _, clue := range p.AllClues {
-		c = stringChecksum(clue, c)
+		c = p.stringChecksum(clue, c)
 	}
 	if p.Version >= "1.3" {
-		c = zStringChecksum(p.Notepad, c)
+		c = p.zStringChecksum(p.Notepad, c)
 	}
 	return c
 }
 
-func stringChecksum(s string, c uint16) uint16 {
-	return checksum(PuzzleBytes(s), c)
+func (p *Puzzle) stringChecksum(s string, c uint16) uint16 {
+	return checksum(p.puzzleBytes(s), c)
 }
 
-func zStringChecksum(s string, c uint16) uint16 {
+func (p *Puzzle) zStringChecksum(s string, c uint16) uint16 {
 	if s == "" {
 		return c
 	}
-	return stringChecksum(s+"\x00", c)
+	return p.stringChecksum(s+"\x00", c)
 }
@@ -1,12 +1,17 @@
 package crossword
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io/fs"
 	"io/ioutil"
+	"os"
 	"path"
 	"reflect"
 	"sort"
 	"testing"
+	"testing/fstest"
 	"time"
 )
 
@@ -248,3 +253,495 @@ func checkStart(t *testing.T, p *Puzzle, dir Direction) {
 		}
 	}
 }
+
+func TestFill(t *testing.T) {
+	p, err := Read(path.Join(testDataDir, "Mar1008.puz"))
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	cases := []struct {
+		x, y    int
+		fill    byte
+		filled  bool
+		correct bool
+	}{
+		{0, 0, 'F', true, true},
+		{3, 1, 'L', true, true},
+		{4, 0, '.', false, false},
+		{1, 2, '-', false, false},
+		{14, 14, '-', false, false},
+	}
+	for _, c := range cases {
+		pos := NewPosition(c.x, c.y)
+		if f := p.Fill(c.x, c.y); f != c.fill {
+			t.Errorf("Fill%v == %q, want %q", pos, f, c.fill)
+		}
+		if f := p.IsFilled(c.x, c.y); f != c.filled {
+			t.Errorf("IsFilled%v == %v, want %v", pos, f, c.filled)
+		}
+		if f := p.IsCorrect(c.x, c.y); f != c.correct {
+			t.Errorf("IsCorrect%v == %v, want %v", pos, f, c.correct)
+		}
+	}
+	p.SetFill(1, 2, 'X')
+	p.SetFill(4, 0, 'X')
+	p.ClearFill(0, 0)
+	puz, err := Encode(p)
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	q, err := Decode(puz)
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	if q.Fill(1, 2) != 'X' || q.IsCorrect(1, 2) {
+		t.Errorf("Fill(1, 2) == %q after SetFill", q.Fill(1, 2))
+	}
+	if !q.IsBlack(4, 0) || q.Fill(4, 0) != '.' {
+		t.Errorf("SetFill changed black square")
+	}
+	if q.IsFilled(0, 0) {
+		t.Errorf("Fill(0, 0) == %q after ClearFill", q.Fill(0, 0))
+	}
+}
+
+func TestRebus(t *testing.T) {
+	p, err := Read(path.Join(testDataDir, "Mar2711.puz"))
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	cases := []struct {
+		dir    Direction
+		n      int
+		i      int
+		answer string
+	}{
+		{Down, 22, 0, "PIN"},
+		{Down, 81, 4, "BALL"},
+		{Across, 1, 0, "B"},
+	}
+	for _, c := range cases {
+		pos := p.Dir[c.dir].Words[c.n][c.i]
+		s := p.AnswerString(pos.X, pos.Y)
+		if s != c.answer {
+			t.Errorf("AnswerString%v == %q, want %q", pos, s, c.answer)
+		}
+		rebus := len(c.answer) > 1
+		if p.IsRebus(pos.X, pos.Y) != rebus {
+			t.Errorf("IsRebus%v == %v, want %v", pos, !rebus, rebus)
+		}
+		if p.Answer(pos.X, pos.Y) != c.answer[0] {
+			t.Errorf("Answer%v == %q, want %q", pos, p.Answer(pos.X, pos.Y), c.answer[0])
+		}
+	}
+}
+
+func TestSetRebus(t *testing.T) {
+	p, err := Read(path.Join(testDataDir, "Mar1420.puz"))
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	word := p.Dir[Across].Words[1]
+	p.setRebus(word[0].X, word[0].Y, "STAR")
+	p.setRebus(word[1].X, word[1].Y, "STAR")
+	p.setRebus(word[2].X, word[2].Y, "MOON")
+	puz, err := Encode(p)
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	q, err := Decode(puz)
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	if len(q.rebusTable) != 2 {
+		t.Errorf("rebus table == %v, want 2 entries", q.rebusTable)
+	}
+	want := "STARSTARMOON" + p.Dir[Across].Answers[1][3:]
+	got := q.Dir[Across].Answers[1]
+	if got != want {
+		t.Errorf("1 %v == %q, want %q", Across, got, want)
+	}
+}
+
+func TestReadRebusTable(t *testing.T) {
+	cases := []struct {
+		data  string
+		table map[int]string
+		err   bool
+	}{
+		{" 0:OCT; 1:JAN;13:DEC;", map[int]string{0: "OCT", 1: "JAN", 13: "DEC"}, false},
+		{" 8:8;", map[int]string{8: "8"}, false},
+		{"", map[int]string{}, false},
+		{" 1 HEART;", nil, true},
+		{"X:HEART;", nil, true},
+		{"255:HEART;", nil, true},
+	}
+	for _, c := range cases {
+		t.Run(c.data, func(t *testing.T) {
+			table, err := readRebusTable([]byte(c.data))
+			if err != nil {
+				if !c.err {
+					t.Errorf("%s", err)
+				}
+				return
+			}
+			if c.err {
+				t.Errorf("readRebusTable(%q) == %v, want error", c.data, table)
+				return
+			}
+			if !reflect.DeepEqual(table, c.table) {
+				t.Errorf("readRebusTable(%q) == %v, want %v", c.data, table, c.table)
+			}
+		})
+	}
+}
+
+func TestTimer(t *testing.T) {
+	cases := []struct {
+		file  string
+		timer *Timer
+	}{
+		{"Mar1008.puz", &Timer{Elapsed: 8, Stopped: false}},
+		{"Sep1208.puz", &Timer{Elapsed: 0, Stopped: true}},
+		{"Mar1420.puz", nil},
+	}
+	for _, c := range cases {
+		t.Run(c.file, func(t *testing.T) {
+			p, err := Read(path.Join(testDataDir, c.file))
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			if !reflect.DeepEqual(p.Timer, c.timer) {
+				t.Errorf("Timer == %+v, want %+v", p.Timer, c.timer)
+			}
+			p.Timer = &Timer{Elapsed: 3723, Stopped: true}
+			puz, err := Encode(p)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			q, err := Decode(puz)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			if !reflect.DeepEqual(q.Timer, p.Timer) {
+				t.Errorf("Timer == %+v after encoding, want %+v", q.Timer, p.Timer)
+			}
+		})
+	}
+}
+
+func TestReadTimer(t *testing.T) {
+	cases := []struct {
+		data  string
+		timer *Timer
+	}{
+		{"8,0", &Timer{Elapsed: 8}},
+		{"1234,1", &Timer{Elapsed: 1234, Stopped: true}},
+		{"", nil},
+		{"8", nil},
+		{"-1,0", nil},
+		{"8,2", nil},
+		{"8,0,0", nil},
+	}
+	for _, c := range cases {
+		t.Run(c.data, func(t *testing.T) {
+			timer, err := readTimer([]byte(c.data))
+			if err != nil {
+				if c.timer != nil {
+					t.Errorf("%s", err)
+				}
+				return
+			}
+			if !reflect.DeepEqual(timer, c.timer) {
+				t.Errorf("readTimer(%q) == %+v, want %+v", c.data, timer, c.timer)
+			}
+		})
+	}
+}
+
+func TestSquareFlags(t *testing.T) {
+	p, err := Read(path.Join(testDataDir, "Mar1420.puz"))
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	cases := []struct {
+		x, y      int
+		flags     SquareFlags
+		circled   bool
+		revealed  bool
+		was       bool
+		incorrect bool
+	}{
+		{0, 0, Circled | Revealed, true, true, false, false},
+		{1, 0, PreviouslyIncorrect | MarkedIncorrect, false, false, true, true},
+		{2, 0, PreviouslyIncorrect | Revealed, false, true, true, false},
+		{3, 0, 0x08, false, false, false, false},
+		{4, 0, 0, false, false, false, false},
+	}
+	for _, c := range cases {
+		p.SetFlags(c.x, c.y, c.flags)
+	}
+	puz, err := Encode(p)
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	q, err := Decode(puz)
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	for _, c := range cases {
+		pos := NewPosition(c.x, c.y)
+		if f := q.Flags(c.x, c.y); f != c.flags {
+			t.Errorf("Flags%v == %02X, want %02X", pos, f, c.flags)
+		}
+		if q.IsCircled(c.x, c.y) != c.circled {
+			t.Errorf("IsCircled%v == %v, want %v", pos, !c.circled, c.circled)
+		}
+		if q.IsRevealed(c.x, c.y) != c.revealed {
+			t.Errorf("IsRevealed%v == %v, want %v", pos, !c.revealed, c.revealed)
+		}
+		if q.WasIncorrect(c.x, c.y) != c.was {
+			t.Errorf("WasIncorrect%v == %v, want %v", pos, !c.was, c.was)
+		}
+		if q.IsMarkedIncorrect(c.x, c.y) != c.incorrect {
+			t.Errorf("IsMarkedIncorrect%v == %v, want %v", pos, !c.incorrect, c.incorrect)
+		}
+	}
+}
+
+func TestUserRebus(t *testing.T) {
+	p, err := Read(path.Join(testDataDir, "Mar2711.puz"))
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	pin := p.Dir[Down].Words[22][0]
+	ball := p.Dir[Down].Words[81][4]
+	p.SetFillString(pin.X, pin.Y, "PIN")
+	p.SetFillString(ball.X, ball.Y, "BELL")
+	puz, err := Encode(p)
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	q, err := Decode(puz)
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	if s := q.FillString(pin.X, pin.Y); s != "PIN" {
+		t.Errorf("FillString%v == %q, want %q", pin, s, "PIN")
+	}
+	if c := q.Fill(pin.X, pin.Y); c != 'P' {
+		t.Errorf("Fill%v == %q, want %q", pin, c, 'P')
+	}
+	if !q.IsCorrect(pin.X, pin.Y) {
+		t.Errorf("IsCorrect%v == false, want true", pin)
+	}
+	if q.IsCorrect(ball.X, ball.Y) {
+		t.Errorf("IsCorrect%v == true, want false", ball)
+	}
+	q.SetFill(ball.X, ball.Y, 'B')
+	if s := q.FillString(ball.X, ball.Y); s != "B" {
+		t.Errorf("FillString%v == %q after SetFill, want %q", ball, s, "B")
+	}
+}
+
+func TestReadUserRebus(t *testing.T) {
+	p := &Puzzle{Width: 2, Height: 2}
+	cases := []struct {
+		data string
+		err  bool
+	}{
+		{"\x00\x00\x00\x00", false},
+		{"AB\x00\x00\x00CD\x00", false},
+		{"\x00\x00\x00", true},
+		{"\x00\x00\x00\x00X", true},
+	}
+	for _, c := range cases {
+		t.Run(c.data, func(t *testing.T) {
+			g, err := p.readUserRebus([]byte(c.data))
+			if err != nil {
+				if !c.err {
+					t.Errorf("%s", err)
+				}
+				return
+			}
+			if c.err {
+				t.Errorf("readUserRebus(%q) == %q, want error", c.data, g)
+			}
+		})
+	}
+}
+
+func makeExtension(code string, data string) []byte {
+	var buf bytes.Buffer
+	writeExtension(&buf, code, []byte(data))
+	return buf.Bytes()
+}
+
+func TestUnknownExtensions(t *testing.T) {
+	orig, err := ioutil.ReadFile(path.Join(testDataDir, "Mar1420.puz"))
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	var puz []byte
+	puz = append(puz, orig...)
+	puz = append(puz, makeExtension("XTRA", "private data")...)
+	puz = append(puz, makeExtension("LTIM", "42,1")...)
+	puz = append(puz, makeExtension("ZZZZ", "")...)
+	p, err := Decode(puz)
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	want := []Extension{
+		{Code: "XTRA", Data: []byte("private data")},
+		{Code: "ZZZZ", Data: []byte{}},
+	}
+	if !reflect.DeepEqual(p.Extensions, want) {
+		t.Errorf("Extensions == %+v, want %+v", p.Extensions, want)
+	}
+	if p.Timer == nil || p.Timer.Elapsed != 42 {
+		t.Errorf("Timer == %+v, want 42 seconds", p.Timer)
+	}
+	enc, err := Encode(p)
+	if err != nil {
+		t.Errorf("%s", err)
+		return
+	}
+	if !bytes.Equal(enc, puz) {
+		t.Errorf("encoded puzzle differs from original")
+	}
+	_, _, err = DecodeWithOptions(puz, DecodeOptions{Strict: true})
+	if err == nil {
+		t.Errorf("strict decoding accepted unknown extensions")
+	}
+	bad := append(puz, makeExtension("XBAD", "data")...)
+	bad[len(bad)-2] ^= 0xFF
+	_, err = Decode(bad)
+	if err == nil {
+		t.Errorf("decoding accepted extension with bad checksum")
+	}
+}
+
+func TestLargeNumbers(t *testing.T) {
+	// A 40×40 grid with a black square at every third position in each direction
+	// has far more than 255 numbered squares.
+	const size = 40
+	p := &Puzzle{Width: size, Height: size}
+	p.solution = p.MakeGrid()
+	for y := range p.solution {
+		for x := range p.solution[y] {
+			if x%3 == 2 && y%3 == 2 {
+				p.solution[y][x] = blackSquare
+			} else {
+				p.solution[y][x] = 'A'
+			}
+		}
+	}
+	numClues := 0
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if p.IsBlack(x, y) {
+				continue
+			}
+			if p.IsBlack(x-1, y) && !p.IsBlack(x+1, y) {
+				numClues++
+			}
+			if p.IsBlack(x, y-1) && !p.IsBlack(x, y+1) {
+				numClues++
+			}
+		}
+	}
+	p.AllClues = make([]string, numClues)
+	p.indexClues()
+	max := 0
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if n := p.SquareNumber(x, y); n > max {
+				max = n
+			}
+		}
+	}
+	if max <= 255 {
+		t.Fatalf("largest square number is %d, want more than 255", max)
+	}
+	for dir := Across; dir <= Down; dir++ {
+		d := p.Dir[dir]
+		last := d.Numbers[len(d.Numbers)-1]
+		if last <= 255 {
+			continue
+		}
+		for _, pos := range d.Words[last] {
+			if d.Start[pos.Y][pos.X] != last {
+				t.Errorf("Start%v == %d, want %d", pos, d.Start[pos.Y][pos.X], last)
+			}
+		}
+		if p.PositionNumber(d.Positions[last]) != last {
+			t.Errorf("number at %v == %d, want %d", d.Positions[last], p.PositionNumber(d.Positions[last]), last)
+		}
+	}
+}
+
+func TestDecodeReader(t *testing.T) {
+	file := path.Join(testDataDir, "Mar1420.puz")
+	want, err := Read(file)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer f.Close()
+	got, err := DecodeReader(f)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got.Title != want.Title || got.Solution() != want.Solution() {
+		t.Errorf("DecodeReader produced %q, want %q", got.Title, want.Title)
+	}
+	_, err = DecodeReader(bytes.NewReader(bytes.Repeat([]byte("not a puzzle "), 10)))
+	if !errors.Is(err, ErrNoHeader) {
+		t.Errorf("DecodeReader of invalid data returned %v, want %v", err, ErrNoHeader)
+	}
+}
+
+func TestReadFS(t *testing.T) {
+	base := "Mar1420.puz"
+	want, err := Read(path.Join(testDataDir, base))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	got, err := ReadFS(os.DirFS(testDataDir), base)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got.Title != want.Title || got.Solution() != want.Solution() {
+		t.Errorf("ReadFS produced %q, want %q", got.Title, want.Title)
+	}
+	fsys := fstest.MapFS{"bad.puz": &fstest.MapFile{Data: bytes.Repeat([]byte("not a puzzle "), 10)}}
+	_, err = ReadFS(fsys, "bad.puz")
+	if !errors.Is(err, ErrNoHeader) {
+		t.Errorf("ReadFS of invalid file returned %v, want %v", err, ErrNoHeader)
+	}
+	_, err = ReadFS(fsys, "missing.puz")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadFS of missing file returned %v, want %v", err, fs.ErrNotExist)
+	}
+}
@@ -1,17 +1,21 @@
 package crossword
 
 import (
-	"bytes"
 	"fmt"
 	"math"
 	"sort"
+	"strings"
+	"unicode"
 
 	"github.com/jung-kurt/gofpdf"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/unicode/norm"
 )
 
 const (
-	font            = "Helvetica"
+	defaultFont     = "Helvetica"
 	blackLevel      = 0.70 // ink-saving level: 1 = solid black squares
+	shadeLevel      = 0.15 // level for shaded squares
 	marginPoints    = 18.0
 	titlePoints     = 13.0
 	minColumns      = 2
@@ -29,12 +33,23 @@ const (
 )
 
 type (
+	// RenderOptions control how a puzzle is rendered.
+	RenderOptions struct {
+		// Font is the family name of a Unicode TrueType font that has been added
+		// to the PDF document, in both regular and bold styles, with AddUTF8Font
+		// or AddUTF8FontFromBytes. If it is empty, Helvetica is used,
+		// and text that cannot be represented in Windows-1252 is approximated.
+		Font string
+	}
+
 	RenderContext struct {
 		Layouts    []Layout // in order of increasing NumColumns
 		BestLayout int
 
 		puz            *Puzzle
 		pdf            *gofpdf.Fpdf
+		font           string
+		utf8           bool    // font is a Unicode font
 		pageWidth      float64 // page width
 		pageHeight     float64 // page height
 		margin         float64
@@ -73,7 +88,15 @@ type (
 )
 
 func (p *Puzzle) NewRenderContext(pdf *gofpdf.Fpdf) *RenderContext {
-	r := RenderContext{puz: p, pdf: pdf}
+	return p.NewRenderContextWithOptions(pdf, RenderOptions{})
+}
+
+// NewRenderContextWithOptions is like NewRenderContext but renders the puzzle as specified by opts.
+func (p *Puzzle) NewRenderContextWithOptions(pdf *gofpdf.Fpdf, opts RenderOptions) *RenderContext {
+	r := RenderContext{puz: p, pdf: pdf, font: defaultFont}
+	if opts.Font != "" {
+		r.font, r.utf8 = opts.Font, true
+	}
 	r.pageWidth, r.pageHeight = pdf.GetPageSize()
 	r.margin = pdf.PointConvert(marginPoints)
 	r.titleHeight = pdf.PointConvert(titlePoints)
@@ -144,35 +167,37 @@ func (r *RenderContext) renderLayout(i int) {
 func (r *RenderContext) drawTitle() {
 	puz := r.puz
 	pdf := r.pdf
-	info := PuzzleString(puz.Author)
-	pdf.SetFont(font, "", 0.9*titlePoints)
+	info := r.text(puz.Author)
+	pdf.SetFont(r.font, "", 0.9*titlePoints)
 	w := pdf.GetStringWidth(info)
 	x := r.pageWidth - r.margin - w
 	y := r.margin + r.columnSep
 	if r.rendering {
 		pdf.Text(x, y, info)
 	}
-	title := PuzzleBytes(puz.Title)
-	pdf.SetFont(font, "B", titlePoints)
-	lines := pdf.SplitLines(title, x-2*r.columnSep)
+	title := r.text(puz.Title)
+	pdf.SetFont(r.font, "B", titlePoints)
+	lines := r.splitLines(title, x-2*r.columnSep)
 	// Allow first line to extend up to author info.
-	if r.rendering {
-		pdf.Text(r.margin, y, string(lines[0]))
+	if r.rendering && len(lines) != 0 {
+		pdf.Text(r.margin, y, lines[0])
 	}
 	y += r.titleHeight
-	lines = lines[1:]
+	if len(lines) != 0 {
+		lines = lines[1:]
+	}
 	if len(lines) == 0 {
 		r.titleBottom = math.Max(y+0.25*r.titleHeight, r.puzzleTop)
 		return
 	}
 	if len(lines) > 1 && !r.wide {
 		// Re-break subsequent lines to fit to the left of the grid.
-		rest := bytes.Join(lines, []byte{' '})
-		lines = pdf.SplitLines(rest, r.pageWidth-r.renderWidth-2*r.margin)
+		rest := strings.Join(lines, " ")
+		lines = r.splitLines(rest, r.pageWidth-r.renderWidth-2*r.margin)
 	}
 	for _, v := range lines {
 		if r.rendering {
-			pdf.Text(r.margin, y, string(v))
+			pdf.Text(r.margin, y, v)
 		}
 		y += r.titleHeight
 	}
@@ -200,18 +225,24 @@ func (r *RenderContext) drawGrid() {
 	for x := 0.0; x <= puzWidth; x++ {
 		pdf.Line(x, 0, x, puzHeight)
 	}
-	// Draw black squares, numbers, and circles.
-	v := int(math.Round((1 - blackLevel) * 255))
-	pdf.SetFillColor(v, v, v)
+	// Draw black and shaded squares, numbers, and circles.
+	black := int(math.Round((1 - blackLevel) * 255))
+	shade := int(math.Round((1 - shadeLevel) * 255))
 	numberSize := 0.3 // scaled by 1/sq
-	pdf.SetFont(font, "", numberSize)
+	pdf.SetFont(r.font, "", numberSize)
 	for y := 0.0; y < puzHeight; y++ {
 		for x := 0.0; x < puzWidth; x++ {
 			i, j := int(x), int(y)
 			if puz.IsBlack(i, j) {
+				pdf.SetFillColor(black, black, black)
 				pdf.Rect(x, y, 1, 1, "F")
 				continue
 			}
+			if puz.IsShaded(i, j) {
+				// Redraw the border, since the fill covers half the line width.
+				pdf.SetFillColor(shade, shade, shade)
+				pdf.Rect(x, y, 1, 1, "FD")
+			}
 			n := puz.SquareNumber(i, j)
 			if n != 0 {
 				pdf.Text(x+0.05, y+numberSize, fmt.Sprintf("%d", n))
@@ -271,12 +302,12 @@ func (r *RenderContext) doClues(dir Direction) {
 	pdf := r.pdf
 	ch := r.clueLineHeight
 	rendering := r.rendering
-	pdf.SetFont(font, "B", 0.8*r.cluePoints)
+	pdf.SetFont(r.font, "B", 0.8*r.cluePoints)
 	if rendering {
 		pdf.Text(r.x, r.y, dir.String())
 	}
 	r.y += 1.25 * ch
-	pdf.SetFont(font, "", r.cluePoints)
+	pdf.SetFont(r.font, "", r.cluePoints)
 	for _, n := range numbers {
 		h, lines := r.clueHeight(clues[n])
 		if r.y+h > r.pageHeight-r.margin {
@@ -286,15 +317,15 @@ func (r *RenderContext) doClues(dir Direction) {
 			}
 		}
 		s := makeClueNumber(n)
-		pdf.SetFont(font, "B", r.numberPoints)
+		pdf.SetFont(r.font, "B", r.numberPoints)
 		w := pdf.GetStringWidth(s)
 		if rendering {
 			pdf.Text(r.x-w, r.y, s)
 		}
-		pdf.SetFont(font, "", r.cluePoints)
+		pdf.SetFont(r.font, "", r.cluePoints)
 		for _, v := range lines {
 			if rendering {
-				pdf.Text(r.x, r.y, string(v))
+				pdf.Text(r.x, r.y, v)
 			}
 			r.y += ch
 		}
@@ -304,8 +335,8 @@ func (r *RenderContext) doClues(dir Direction) {
 
 // clueHeight calculates the height required to render a clue.
 // It returns the height and the split lines for rendering.
-func (r *RenderContext) clueHeight(clue string) (float64, [][]byte) {
-	lines := r.pdf.SplitLines(PuzzleBytes(clue), r.columnWidth-r.numberWidth)
+func (r *RenderContext) clueHeight(clue string) (float64, []string) {
+	lines := r.splitLines(r.text(clue), r.columnWidth-r.numberWidth)
 	h := (float64(len(lines)) + interClueFrac) * r.clueLineHeight
 	return h, lines
 }
@@ -313,7 +344,7 @@ func (r *RenderContext) clueHeight(clue string) (float64, [][]byte) {
 func (r *RenderContext) setNumberWidth() {
 	pdf := r.pdf
 	r.numberPoints = 0.8 * r.cluePoints
-	pdf.SetFont(font, "B", r.numberPoints)
+	pdf.SetFont(r.font, "B", r.numberPoints)
 	max := 0.0
 	for n := 1; n <= r.puz.Height*r.puz.Width; n++ {
 		w := pdf.GetStringWidth(makeClueNumber(n))
@@ -412,7 +443,7 @@ func (r *RenderContext) markPage(i int) {
 	layout := r.Layouts[i]
 	cw := r.getColumnWidth(layout.NumColumns)
 	info := fmt.Sprintf("%.2fpt %.0f %s ", layout.PointSize, cw, layout.Score)
-	pdf.SetFont(font, "", 7)
+	pdf.SetFont(r.font, "", 7)
 	x, y := r.pageWidth-r.margin, r.pageHeight-0.5*r.margin
 	w := pdf.GetStringWidth(info)
 	pdf.Text(x-w, y, info)
@@ -422,6 +453,69 @@ func (r *RenderContext) markPage(i int) {
 	}
 }
 
+// text converts s to the encoding required by the current font.
+func (r *RenderContext) text(s string) string {
+	if !r.utf8 {
+		return pdfString(s)
+	}
+	// Unicode fonts only provide glyph widths for the Basic Multilingual Plane.
+	return strings.Map(func(c rune) rune {
+		if c > 0xFFFF {
+			return '?'
+		}
+		return c
+	}, s)
+}
+
+// splitLines splits text that has been converted by r.text into lines
+// that fit within width w when rendered in the current font.
+func (r *RenderContext) splitLines(s string, w float64) []string {
+	if r.utf8 {
+		return r.pdf.SplitText(s, w)
+	}
+	var lines []string
+	for _, v := range r.pdf.SplitLines([]byte(s), w) {
+		lines = append(lines, string(v))
+	}
+	return lines
+}
+
+// pdfString converts s to the Windows-1252 encoding used by the standard PDF fonts,
+// which are used unless a Unicode font is specified in the RenderOptions.
+// Characters outside that encoding are replaced by their unaccented base letters
+// if possible, and by '?' otherwise.
+func pdfString(s string) string {
+	var buf strings.Builder
+	for _, c := range s {
+		if b, ok := charmap.Windows1252.EncodeRune(c); ok {
+			buf.WriteByte(b)
+			continue
+		}
+		buf.WriteString(baseLetters(c))
+	}
+	return buf.String()
+}
+
+// baseLetters returns the Windows-1252 encoding of c with any accents removed,
+// or "?" if there is none.
+func baseLetters(c rune) string {
+	var v []byte
+	for _, d := range norm.NFD.String(string(c)) {
+		if unicode.Is(unicode.Mn, d) {
+			continue
+		}
+		b, ok := charmap.Windows1252.EncodeRune(d)
+		if !ok {
+			return "?"
+		}
+		v = append(v, b)
+	}
+	if len(v) == 0 {
+		return "?"
+	}
+	return string(v)
+}
+
 // sort.Interface for Layouts using NumColumns as sort key.
 func (v Layouts) Len() int           { return len(v) }
 func (v Layouts) Swap(i, j int)      { v[i], v[j] = v[j], v[i] }
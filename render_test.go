@@ -1,7 +1,12 @@
 package crossword
 
 import (
+	"bytes"
+	"os"
+	"os/exec"
 	"path"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 
@@ -107,3 +112,55 @@ func BenchmarkRender(b *testing.B) {
 		p.NewRenderContext(pdf)
 	}
 }
+
+func TestPDFString(t *testing.T) {
+	cases := []struct {
+		s    string
+		want string
+	}{
+		{"Plain ASCII", "Plain ASCII"},
+		{"Café “quoted”", "Caf\xe9 \x93quoted\x94"},
+		{"Łódź, Dvořák", "?\xf3dz, Dvor\xe1k"},
+		{"Снег ☃", "???? ?"},
+	}
+	for _, c := range cases {
+		s := pdfString(c.s)
+		if s != c.want {
+			t.Errorf("pdfString(%q) == %q, want %q", c.s, s, c.want)
+		}
+	}
+}
+
+func TestRenderUnicodeFont(t *testing.T) {
+	// Use the DejaVu font distributed with gofpdf.
+	out, err := exec.Command("go", "list", "-m", "-f", "{{.Dir}}", "github.com/jung-kurt/gofpdf").Output()
+	if err != nil {
+		t.Skipf("cannot find gofpdf module: %s", err)
+	}
+	fontDir := filepath.Join(strings.TrimSpace(string(out)), "font")
+	if _, err := os.Stat(filepath.Join(fontDir, "DejaVuSansCondensed.ttf")); err != nil {
+		t.Skipf("%s", err)
+	}
+	title := "Снег ☃ 𝄞"
+	p, err := DecodeText([]byte(strings.Replace(sampleText, "Sample Puzzle", title, 1)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	pdf := gofpdf.New("L", "pt", "Letter", fontDir)
+	pdf.AddUTF8Font("DejaVu", "", "DejaVuSansCondensed.ttf")
+	pdf.AddUTF8Font("DejaVu", "B", "DejaVuSansCondensed-Bold.ttf")
+	rc := p.NewRenderContextWithOptions(pdf, RenderOptions{Font: "DejaVu"})
+	if len(rc.Layouts) == 0 {
+		t.Fatalf("failed to find any layout that fits")
+	}
+	rc.Render()
+	var buf bytes.Buffer
+	err = pdf.Output(&buf)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	want := "Снег ☃ ?"
+	if s := rc.text(title); s != want {
+		t.Errorf("text(%q) == %q, want %q", title, s, want)
+	}
+}
@@ -0,0 +1,425 @@
+package crossword
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Tags used in the AcrossLite text format.
+const (
+	textTag   = "<ACROSS PUZZLE>"
+	textTagV2 = "<ACROSS PUZZLE V2>"
+)
+
+// rebusMarkers are the characters that EncodeText uses
+// to mark rebus squares in the grid, in order of preference.
+const rebusMarkers = "1234567890@#$%&*+=?!~^"
+
+// textSection holds the lines of a section of a puzzle in text format.
+type textSection struct {
+	line  int // line number of the section tag
+	lines []string
+}
+
+// ReadText reads the named file in AcrossLite text format.
+func ReadText(file string) (*Puzzle, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	p, err := DecodeText(data)
+	if err != nil {
+		err = fmt.Errorf("%s: %w", file, err)
+	}
+	return p, err
+}
+
+// DecodeText decodes a puzzle in AcrossLite text format.
+// The text may be encoded in UTF-8 or Windows-1252.
+func DecodeText(data []byte) (*Puzzle, error) {
+	sections, err := splitText(textString(data))
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range []string{"SIZE", "GRID", "ACROSS", "DOWN"} {
+		if sections[tag] == nil {
+			return nil, fmt.Errorf("missing <%s> section", tag)
+		}
+	}
+	var p Puzzle
+	p.Title = textLine(sections["TITLE"])
+	p.Author = textLine(sections["AUTHOR"])
+	p.Copyright = textLine(sections["COPYRIGHT"])
+	if p.Copyright != "" && !strings.Contains(p.Copyright, "©") && !strings.Contains(strings.ToLower(p.Copyright), "copyright") {
+		// AcrossLite adds the copyright symbol itself.
+		p.Copyright = "© " + p.Copyright
+	}
+	if s := sections["NOTEPAD"]; s != nil {
+		p.Notepad = strings.Join(s.lines, "\n")
+	}
+	err = p.readTextSize(sections["SIZE"])
+	if err != nil {
+		return nil, err
+	}
+	rebus, mark, err := readTextRebus(sections["REBUS"])
+	if err != nil {
+		return nil, err
+	}
+	err = p.readTextGrid(sections["GRID"], rebus, mark)
+	if err != nil {
+		return nil, err
+	}
+	p.AllClues, err = p.mergeClues(sections["ACROSS"].lines, sections["DOWN"].lines)
+	if err != nil {
+		return nil, err
+	}
+	p.NumClues = len(p.AllClues)
+	p.setVersion()
+	p.indexClues()
+	return &p, nil
+}
+
+// textString converts text in UTF-8 (with or without a byte order mark)
+// or Windows-1252 to a string.
+func textString(data []byte) string {
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	s, _ := charmap.Windows1252.NewDecoder().String(string(data))
+	return s
+}
+
+// splitText divides the text into sections indexed by tag.
+// Blank lines are removed, except within the notepad.
+func splitText(text string) (map[string]*textSection, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	sections := make(map[string]*textSection)
+	var cur *textSection
+	tag := ""
+	for i, line := range lines {
+		n := i + 1
+		trimmed := strings.TrimSpace(line)
+		if cur == nil {
+			if trimmed == "" {
+				continue
+			}
+			if trimmed != textTag && trimmed != textTagV2 {
+				return nil, fmt.Errorf("line %d: text does not begin with %s", n, textTag)
+			}
+			cur = &textSection{line: n}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "<") && strings.HasSuffix(trimmed, ">") {
+			tag = strings.ToUpper(trimmed[1 : len(trimmed)-1])
+			if !isTextTag(tag) {
+				return nil, fmt.Errorf("line %d: unknown section %s", n, trimmed)
+			}
+			if sections[tag] != nil {
+				return nil, fmt.Errorf("line %d: duplicate section %s", n, trimmed)
+			}
+			cur = &textSection{line: n}
+			sections[tag] = cur
+			continue
+		}
+		if tag == "NOTEPAD" {
+			cur.lines = append(cur.lines, strings.TrimRight(strings.TrimPrefix(line, "\t"), " \t"))
+			continue
+		}
+		if trimmed == "" {
+			continue
+		}
+		if tag == "" {
+			return nil, fmt.Errorf("line %d: text outside of any section", n)
+		}
+		cur.lines = append(cur.lines, trimmed)
+	}
+	if cur == nil {
+		return nil, fmt.Errorf("text does not begin with %s", textTag)
+	}
+	if s := sections["NOTEPAD"]; s != nil {
+		for len(s.lines) != 0 && s.lines[len(s.lines)-1] == "" {
+			s.lines = s.lines[:len(s.lines)-1]
+		}
+	}
+	return sections, nil
+}
+
+func isTextTag(tag string) bool {
+	switch tag {
+	case "TITLE", "AUTHOR", "COPYRIGHT", "SIZE", "GRID", "REBUS", "ACROSS", "DOWN", "NOTEPAD":
+		return true
+	}
+	return false
+}
+
+// textLine returns the contents of a single-line section.
+func textLine(s *textSection) string {
+	if s == nil {
+		return ""
+	}
+	return strings.Join(s.lines, " ")
+}
+
+func (p *Puzzle) readTextSize(s *textSection) error {
+	size := strings.ToLower(strings.ReplaceAll(textLine(s), " ", ""))
+	i := strings.IndexByte(size, 'x')
+	if i == -1 {
+		return fmt.Errorf("line %d: malformed size %q", s.line, textLine(s))
+	}
+	w, err1 := strconv.Atoi(size[:i])
+	h, err2 := strconv.Atoi(size[i+1:])
+	if err1 != nil || err2 != nil || w <= 0 || h <= 0 {
+		return fmt.Errorf("line %d: malformed size %q", s.line, textLine(s))
+	}
+	p.Width, p.Height = w, h
+	return nil
+}
+
+// readTextRebus parses the <REBUS> section, which contains an optional "MARK;" line,
+// indicating that lowercase letters in the grid are circled, and entries of the form
+// "C:REBUS:R", where C is the character marking the rebus squares in the grid
+// and R is the single letter used in their place by programs that lack rebus support.
+func readTextRebus(s *textSection) (map[byte][2]string, bool, error) {
+	rebus := make(map[byte][2]string)
+	mark := false
+	if s == nil {
+		return rebus, mark, nil
+	}
+	for i, line := range s.lines {
+		if strings.EqualFold(line, "MARK;") {
+			mark = true
+			continue
+		}
+		f := strings.Split(line, ":")
+		if len(f) < 2 || len(f) > 3 || len(f[0]) != 1 || f[1] == "" {
+			return nil, false, fmt.Errorf("line %d: malformed rebus entry %q", s.line+i+1, line)
+		}
+		c := f[0][0]
+		if isLetter(c) || c == blackSquare {
+			return nil, false, fmt.Errorf("line %d: rebus marker %q is not allowed", s.line+i+1, c)
+		}
+		long := strings.ToUpper(f[1])
+		short := long[:1]
+		if len(f) == 3 && f[2] != "" {
+			short = strings.ToUpper(f[2][:1])
+		}
+		rebus[c] = [2]string{long, short}
+	}
+	return rebus, mark, nil
+}
+
+func (p *Puzzle) readTextGrid(s *textSection, rebus map[byte][2]string, mark bool) error {
+	if len(s.lines) != p.Height {
+		return fmt.Errorf("line %d: grid has %d rows instead of %d", s.line, len(s.lines), p.Height)
+	}
+	p.solution = p.MakeGrid()
+	for y, row := range s.lines {
+		if len(row) != p.Width {
+			return fmt.Errorf("line %d: grid row has %d columns instead of %d", s.line+y+1, len(row), p.Width)
+		}
+		for x := 0; x < p.Width; x++ {
+			c := row[x]
+			if c >= utf8.RuneSelf {
+				return fmt.Errorf("line %d: grid contains non-ASCII character", s.line+y+1)
+			}
+			if r, ok := rebus[c]; ok {
+				p.addRebus(x, y, r[0], r[1][0])
+				continue
+			}
+			p.solution[y][x] = c
+			if 'a' <= c && c <= 'z' {
+				p.solution[y][x] = c - 'a' + 'A'
+				if mark {
+					p.SetFlags(x, y, Circled)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func isLetter(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z'
+}
+
+// mergeClues combines the across and down clues, each in increasing order of number,
+// into a single list in the order used by AllClues.
+func (p *Puzzle) mergeClues(across, down []string) ([]string, error) {
+	clues := [][]string{across, down}
+	var all []string
+	count := make([]int, 2)
+	for _, e := range p.clueEntries() {
+		i := count[e.dir]
+		if i < len(clues[e.dir]) {
+			all = append(all, clues[e.dir][i])
+		}
+		count[e.dir]++
+	}
+	for dir, v := range clues {
+		if len(v) != count[dir] {
+			return nil, fmt.Errorf("%d %v clues for %d entries", len(v), Direction(dir), count[dir])
+		}
+	}
+	return all, nil
+}
+
+// splitClues divides AllClues into the across and down clues,
+// each in increasing order of number.
+func (p *Puzzle) splitClues() ([]string, []string, error) {
+	entries := p.clueEntries()
+	if len(entries) != len(p.AllClues) {
+		return nil, nil, fmt.Errorf("puzzle has %d clues for %d entries", len(p.AllClues), len(entries))
+	}
+	clues := make([][]string, 2)
+	for i, e := range entries {
+		clues[e.dir] = append(clues[e.dir], p.AllClues[i])
+	}
+	return clues[Across], clues[Down], nil
+}
+
+// WriteText encodes the puzzle in AcrossLite text format and writes it to the named file.
+func WriteText(file string, p *Puzzle) error {
+	text, err := EncodeText(p)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, text, 0644)
+}
+
+// EncodeText returns the AcrossLite text representation of the puzzle.
+// The text is encoded in UTF-8 for puzzles of version 2.0 or later,
+// and in Windows-1252 otherwise.
+// Circles in rebus squares cannot be represented and are omitted.
+func EncodeText(p *Puzzle) ([]byte, error) {
+	if p.Scrambled {
+		return nil, fmt.Errorf("cannot encode locked puzzle as text")
+	}
+	if !p.hasGridSize(p.solution) {
+		return nil, fmt.Errorf("solution does not match %d×%d puzzle", p.Width, p.Height)
+	}
+	across, down, err := p.splitClues()
+	if err != nil {
+		return nil, err
+	}
+	grid, entries, mark, err := p.textGrid(blackSquare)
+	if err != nil {
+		return nil, err
+	}
+	var rebus []string
+	for _, r := range entries {
+		rebus = append(rebus, fmt.Sprintf("%c:%s:%c", r.marker, r.answer, r.letter))
+	}
+	var buf strings.Builder
+	if len(rebus) != 0 || mark {
+		buf.WriteString(textTagV2 + "\n")
+	} else {
+		buf.WriteString(textTag + "\n")
+	}
+	writeTextSection(&buf, "TITLE", p.Title)
+	writeTextSection(&buf, "AUTHOR", p.Author)
+	writeTextSection(&buf, "COPYRIGHT", strings.TrimSpace(strings.TrimPrefix(p.Copyright, "©")))
+	writeTextSection(&buf, "SIZE", fmt.Sprintf("%dx%d", p.Width, p.Height))
+	writeTextSection(&buf, "GRID", grid...)
+	if len(rebus) != 0 || mark {
+		if mark {
+			rebus = append([]string{"MARK;"}, rebus...)
+		}
+		writeTextSection(&buf, "REBUS", rebus...)
+	}
+	writeTextSection(&buf, "ACROSS", across...)
+	writeTextSection(&buf, "DOWN", down...)
+	if p.Notepad != "" {
+		notepad := strings.Split(strings.ReplaceAll(p.Notepad, "\r\n", "\n"), "\n")
+		writeTextSection(&buf, "NOTEPAD", notepad...)
+	}
+	v, err := p.textEncoding().NewEncoder().Bytes([]byte(buf.String()))
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode text of version %s puzzle: %w", p.Version, err)
+	}
+	return v, nil
+}
+
+// textRebus is a rebus entry for a grid in text form.
+type textRebus struct {
+	marker byte   // character marking the rebus squares in the grid
+	answer string // complete answer
+	letter byte   // single letter used in the solution grid
+}
+
+// textGrid returns the rows of the grid in text form, with black squares shown as block,
+// together with the rebus entries used and whether lowercase letters mark circles.
+func (p *Puzzle) textGrid(block byte) ([]string, []textRebus, bool, error) {
+	used := map[byte]bool{block: true}
+	for y := 0; y < p.Height; y++ {
+		for x := 0; x < p.Width; x++ {
+			used[p.solution[y][x]] = true
+		}
+	}
+	markers := make(map[string]byte)
+	var rebus []textRebus
+	mark := false
+	rows := make([]string, p.Height)
+	for y := 0; y < p.Height; y++ {
+		row := make([]byte, p.Width)
+		for x := 0; x < p.Width; x++ {
+			c := p.solution[y][x]
+			switch {
+			case p.IsBlack(x, y):
+				c = block
+			case p.IsRebus(x, y):
+				s := p.AnswerString(x, y)
+				m, ok := markers[s]
+				if !ok {
+					i := strings.IndexFunc(rebusMarkers, func(r rune) bool { return !used[byte(r)] })
+					if i == -1 {
+						return nil, nil, false, fmt.Errorf("too many different rebus squares")
+					}
+					m = rebusMarkers[i]
+					used[m] = true
+					markers[s] = m
+					rebus = append(rebus, textRebus{marker: m, answer: s, letter: c})
+				}
+				c = m
+			case p.IsCircled(x, y) && 'A' <= c && c <= 'Z':
+				c = c - 'A' + 'a'
+				mark = true
+			}
+			row[x] = c
+		}
+		rows[y] = string(row)
+	}
+	return rows, rebus, mark, nil
+}
+
+func writeTextSection(buf *strings.Builder, tag string, lines ...string) {
+	fmt.Fprintf(buf, "<%s>\n", tag)
+	for _, line := range lines {
+		fmt.Fprintf(buf, "\t%s\n", line)
+	}
+}
+
+// setVersion sets the version of a puzzle that was not decoded from PUZ format:
+// 2.0 if any of its text cannot be represented in Windows-1252, the default otherwise.
+func (p *Puzzle) setVersion() {
+	text := []string{p.Title, p.Author, p.Copyright, p.Notepad}
+	text = append(text, p.AllClues...)
+	for _, s := range p.rebusTable {
+		text = append(text, s)
+	}
+	enc := charmap.Windows1252.NewEncoder()
+	for _, s := range text {
+		_, err := enc.String(s)
+		if err != nil {
+			p.Version = "2.0"
+			return
+		}
+	}
+	p.Version = defaultVersion
+}
@@ -0,0 +1,242 @@
+package crossword
+
+import (
+	"path"
+	"strings"
+	"testing"
+)
+
+const sampleText = `<ACROSS PUZZLE V2>
+<TITLE>
+	Sample Puzzle
+<AUTHOR>
+	A. Constructor
+<COPYRIGHT>
+	2024 Example Syndicate
+<SIZE>
+	4x4
+<GRID>
+	1aTS
+	ROBE
+	IRON
+	.NEW
+<REBUS>
+	MARK;
+	1:CAT:C
+<ACROSS>
+	Felines plus fish?
+	Bathrobe, e.g.
+	Press
+	Recent
+<DOWN>
+	Animal doctor's charge?
+	Throw
+	Attempts
+	Stitched
+<NOTEPAD>
+	First line of the notepad.
+
+	Last line.
+`
+
+func TestDecodeText(t *testing.T) {
+	p, err := DecodeText([]byte(sampleText))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if p.Title != "Sample Puzzle" || p.Author != "A. Constructor" {
+		t.Errorf("title and author == %q, %q", p.Title, p.Author)
+	}
+	if p.Copyright != "© 2024 Example Syndicate" {
+		t.Errorf("Copyright == %q", p.Copyright)
+	}
+	if p.Width != 4 || p.Height != 4 || p.NumClues != 8 {
+		t.Errorf("puzzle is %d×%d with %d clues, want 4×4 with 8", p.Width, p.Height, p.NumClues)
+	}
+	if p.Version != defaultVersion {
+		t.Errorf("Version == %q, want %q", p.Version, defaultVersion)
+	}
+	if p.Solution() != "CATS\nROBE\nIRON\n.NEW\n" {
+		t.Errorf("solution == %q", p.Solution())
+	}
+	if !p.IsRebus(0, 0) || p.AnswerString(0, 0) != "CAT" {
+		t.Errorf("square (0, 0) == %q, want rebus %q", p.AnswerString(0, 0), "CAT")
+	}
+	if !p.IsCircled(1, 0) || p.IsCircled(2, 0) {
+		t.Errorf("circles are not correct")
+	}
+	want := map[Direction]map[int]string{
+		Across: {1: "CATATS", 5: "ROBE", 6: "IRON", 7: "NEW"},
+		Down:   {1: "CATRI", 2: "AORN", 3: "TBOE", 4: "SENW"},
+	}
+	for dir, answers := range want {
+		for n, answer := range answers {
+			if p.Dir[dir].Answers[n] != answer {
+				t.Errorf("%d %v == %q, want %q", n, dir, p.Dir[dir].Answers[n], answer)
+			}
+		}
+	}
+	if p.Dir[Down].Clues[4] != "Stitched" {
+		t.Errorf("4 %v clue == %q, want %q", Down, p.Dir[Down].Clues[4], "Stitched")
+	}
+	if p.Notepad != "First line of the notepad.\n\nLast line." {
+		t.Errorf("Notepad == %q", p.Notepad)
+	}
+}
+
+func TestEncodeTextAllPuzzles(t *testing.T) {
+	for _, base := range testFiles() {
+		t.Run(base, func(t *testing.T) {
+			p, err := Read(path.Join(testDataDir, base))
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			if p.Scrambled {
+				return
+			}
+			text, err := EncodeText(p)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			q, err := DecodeText(text)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			checkSamePuzzle(t, q, p)
+		})
+	}
+}
+
+// checkSamePuzzle checks that q has the same contents as p,
+// except for features that not all formats can represent.
+func checkSamePuzzle(t *testing.T, q, p *Puzzle) {
+	t.Helper()
+	// Leading and trailing white space and the form of line breaks are not preserved.
+	same := func(a, b string) bool {
+		norm := func(s string) string { return strings.TrimSpace(strings.ReplaceAll(s, "\r\n", "\n")) }
+		return norm(a) == norm(b)
+	}
+	if !same(q.Title, p.Title) || !same(q.Author, p.Author) || !same(q.Copyright, p.Copyright) {
+		t.Errorf("title, author, copyright == %q, %q, %q, want %q, %q, %q", q.Title, q.Author, q.Copyright, p.Title, p.Author, p.Copyright)
+	}
+	if !same(q.Notepad, p.Notepad) {
+		t.Errorf("Notepad == %q, want %q", q.Notepad, p.Notepad)
+	}
+	if q.Width != p.Width || q.Height != p.Height {
+		t.Errorf("size == %d×%d, want %d×%d", q.Width, q.Height, p.Width, p.Height)
+		return
+	}
+	for y := 0; y < p.Height; y++ {
+		for x := 0; x < p.Width; x++ {
+			// The letter stored in the solution grid for a rebus square
+			// is not represented in all formats.
+			if q.Answer(x, y) != p.Answer(x, y) && !p.IsRebus(x, y) {
+				t.Errorf("solution at %v == %q, want %q", NewPosition(x, y), q.Answer(x, y), p.Answer(x, y))
+			}
+			if q.AnswerString(x, y) != p.AnswerString(x, y) {
+				t.Errorf("answer at %v == %q, want %q", NewPosition(x, y), q.AnswerString(x, y), p.AnswerString(x, y))
+			}
+			if q.IsCircled(x, y) != p.IsCircled(x, y) && !p.IsRebus(x, y) {
+				t.Errorf("circle at %v == %v, want %v", NewPosition(x, y), q.IsCircled(x, y), p.IsCircled(x, y))
+			}
+		}
+	}
+	if len(q.AllClues) != len(p.AllClues) {
+		t.Errorf("%d clues, want %d", len(q.AllClues), len(p.AllClues))
+		return
+	}
+	for i := range p.AllClues {
+		if q.AllClues[i] != p.AllClues[i] {
+			t.Errorf("clue %d == %q, want %q", i, q.AllClues[i], p.AllClues[i])
+		}
+	}
+}
+
+func TestDecodeTextErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		edit func(string) string
+		want string
+	}{
+		{"no tag", func(s string) string { return strings.Replace(s, "<ACROSS PUZZLE V2>", "", 1) }, "does not begin with"},
+		{"unknown section", func(s string) string { return strings.Replace(s, "<NOTEPAD>", "<NOTES>", 1) }, "unknown section"},
+		{"duplicate section", func(s string) string { return strings.Replace(s, "<NOTEPAD>", "<TITLE>", 1) }, "duplicate section"},
+		{"missing down", func(s string) string { return s[:strings.Index(s, "<DOWN>")] }, "missing <DOWN>"},
+		{"bad size", func(s string) string { return strings.Replace(s, "4x4", "4 by 4", 1) }, "malformed size"},
+		{"short row", func(s string) string { return strings.Replace(s, "ROBE", "ROB", 1) }, "3 columns instead of 4"},
+		{"extra row", func(s string) string { return strings.Replace(s, ".NEW", ".NEW\n\tABCD", 1) }, "5 rows instead of 4"},
+		{"bad rebus", func(s string) string { return strings.Replace(s, "1:CAT:C", "1CAT", 1) }, "malformed rebus entry"},
+		{"missing clue", func(s string) string { return strings.Replace(s, "\tRecent\n", "", 1) }, "3 ACROSS clues for 4 entries"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := DecodeText([]byte(c.edit(sampleText)))
+			if err == nil {
+				t.Errorf("DecodeText succeeded, want error containing %q", c.want)
+				return
+			}
+			if !strings.Contains(err.Error(), c.want) {
+				t.Errorf("DecodeText returned %q, want error containing %q", err, c.want)
+			}
+		})
+	}
+}
+
+func TestTextCopyright(t *testing.T) {
+	cases := []struct {
+		line string
+		want string
+	}{
+		{"2024 Example Syndicate", "© 2024 Example Syndicate"},
+		{"© 2024 Example Syndicate", "© 2024 Example Syndicate"},
+		{"Copyright 2020 Foo", "Copyright 2020 Foo"},
+		{"2020 Foo, all rights reserved ©", "2020 Foo, all rights reserved ©"},
+	}
+	for _, c := range cases {
+		t.Run(c.line, func(t *testing.T) {
+			p, err := DecodeText([]byte(strings.Replace(sampleText, "\t2024 Example Syndicate", "\t"+c.line, 1)))
+			if err != nil {
+				t.Fatalf("%s", err)
+			}
+			if p.Copyright != c.want {
+				t.Errorf("Copyright == %q, want %q", p.Copyright, c.want)
+			}
+			text, err := EncodeText(p)
+			if err != nil {
+				t.Fatalf("%s", err)
+			}
+			q, err := DecodeText(text)
+			if err != nil {
+				t.Fatalf("%s", err)
+			}
+			if q.Copyright != p.Copyright {
+				t.Errorf("round-trip Copyright == %q, want %q", q.Copyright, p.Copyright)
+			}
+		})
+	}
+}
+
+func TestEncodeTextUTF8(t *testing.T) {
+	p, err := DecodeText([]byte(strings.Replace(sampleText, "Recent", "Nuevo ☀", 1)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if p.Version != "2.0" {
+		t.Errorf("Version == %q, want %q", p.Version, "2.0")
+	}
+	text, err := EncodeText(p)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !strings.Contains(string(text), "\tNuevo ☀\n") {
+		t.Errorf("encoded text does not contain UTF-8 clue")
+	}
+	q, err := DecodeText(text)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	checkSamePuzzle(t, q, p)
+}
@@ -2,12 +2,39 @@ package crossword
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
 )
 
 type (
 	// Key stores a 4-digit decimal key in big-endian order, one digit per byte.
 	Key []uint8
+
+	// UnlockOptions control brute-force unlocking by UnlockContext.
+	UnlockOptions struct {
+		// Workers is the number of goroutines that try keys.
+		// If it is zero, runtime.GOMAXPROCS(0) is used.
+		Workers int
+		// Progress, if non-nil, is called after each batch of keys is tried
+		// with the number of keys tried so far and the total number of keys.
+		// Calls are made from the worker goroutines, but never concurrently.
+		Progress func(tried, total int)
+		// Words is used by UnlockCandidates to judge the plausibility of answers.
+		// If it is nil, a built-in heuristic for English letter patterns is used.
+		Words WordChecker
+	}
+)
+
+const (
+	numKeys = 10000
+
+	// Number of consecutive keys tried by a worker between checks
+	// for cancellation and success.
+	keyBatchSize = 100
 )
 
 func NewKey() Key {
@@ -55,14 +82,17 @@ func (p *Puzzle) Unlock() (int, error) {
 	if !p.Scrambled {
 		return 0, nil
 	}
-	src := p.compressBuffer(p.solution)
+	src, err := p.scrambledLetters()
+	if err != nil {
+		return 0, err
+	}
 	dst := make([]byte, len(src))
 	tmp := make([]byte, len(src))
 	key := NewKey()
 	for {
 		unscramble(src, key, dst, tmp)
 		if p.correctAnswers(dst) {
-			p.solution = p.expandBuffer(dst)
+			p.setSolution(p.expandBuffer(dst))
 			p.Scrambled = false
 			return key.Int(), nil
 		}
@@ -73,6 +103,134 @@ func (p *Puzzle) Unlock() (int, error) {
 	return 0, fmt.Errorf("brute-force unlocking failed")
 }
 
+// UnlockContext is like Unlock, but tries keys in parallel.
+// It stops when the key is found or ctx is done, in which case it returns ctx.Err().
+// As with Unlock, the smallest key that unlocks the puzzle is returned.
+func (p *Puzzle) UnlockContext(ctx context.Context, opts UnlockOptions) (int, error) {
+	if !p.Scrambled {
+		return 0, nil
+	}
+	src, err := p.scrambledLetters()
+	if err != nil {
+		return 0, err
+	}
+	keys, err := p.findKeys(ctx, src, opts, false)
+	if err != nil {
+		return 0, err
+	}
+	key, _ := NewKeyFromInt(keys[0])
+	dst := make([]byte, len(src))
+	tmp := make([]byte, len(src))
+	unscramble(src, key, dst, tmp)
+	p.setSolution(p.expandBuffer(dst))
+	p.Scrambled = false
+	return keys[0], nil
+}
+
+// findKeys tries keys in parallel to unscramble src, as specified by opts.
+// If all is false, it returns only the smallest key that matches the scrambled checksum;
+// otherwise it returns all such keys in increasing order.
+func (p *Puzzle) findKeys(ctx context.Context, src []byte, opts UnlockOptions, all bool) ([]int, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	u := unlocker{
+		p:     p,
+		src:   src,
+		opts:  opts,
+		all:   all,
+		found: numKeys,
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u.work(ctx)
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if !all && u.found != numKeys {
+		u.matches = []int{u.found}
+	}
+	if len(u.matches) == 0 {
+		return nil, fmt.Errorf("brute-force unlocking failed")
+	}
+	sort.Ints(u.matches)
+	return u.matches, nil
+}
+
+// unlocker holds the state shared by the workers in findKeys.
+type unlocker struct {
+	p    *Puzzle
+	src  []byte
+	opts UnlockOptions
+	all  bool  // whether to find all matching keys
+	next int64 // first key of the next batch to be tried
+
+	mu      sync.Mutex
+	found   int   // smallest key found so far, or numKeys
+	matches []int // keys found so far, if all is true
+	tried   int
+}
+
+// work tries batches of keys in increasing order until the key space is exhausted,
+// a key smaller than the remaining ones has been found (unless all keys are wanted),
+// or ctx is done.
+func (u *unlocker) work(ctx context.Context) {
+	dst := make([]byte, len(u.src))
+	tmp := make([]byte, len(u.src))
+	for ctx.Err() == nil {
+		start := int(atomic.AddInt64(&u.next, keyBatchSize) - keyBatchSize)
+		if start >= u.smallestFound() {
+			return
+		}
+		key, _ := NewKeyFromInt(start)
+		n := 0
+		for k := start; k < start+keyBatchSize && k < numKeys; k++ {
+			unscramble(u.src, key, dst, tmp)
+			n++
+			if u.p.correctAnswers(dst) {
+				u.foundKey(k)
+				if !u.all {
+					break
+				}
+			}
+			key.Next()
+		}
+		u.report(n)
+	}
+}
+
+func (u *unlocker) smallestFound() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.found
+}
+
+func (u *unlocker) foundKey(k int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.all {
+		u.matches = append(u.matches, k)
+	} else if k < u.found {
+		u.found = k
+	}
+}
+
+func (u *unlocker) report(n int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.tried += n
+	if u.opts.Progress != nil {
+		u.opts.Progress(u.tried, numKeys)
+	}
+}
+
 func (p *Puzzle) UnlockWithKey(k int) error {
 	if !p.Scrambled {
 		if k == 0 {
@@ -84,18 +242,100 @@ func (p *Puzzle) UnlockWithKey(k int) error {
 	if err != nil {
 		return err
 	}
-	src := p.compressBuffer(p.solution)
+	src, err := p.scrambledLetters()
+	if err != nil {
+		return err
+	}
 	dst := make([]byte, len(src))
 	tmp := make([]byte, len(src))
 	unscramble(src, key, dst, tmp)
 	if !p.correctAnswers(dst) {
 		return fmt.Errorf("key %04d does not unlock this puzzle", k)
 	}
-	p.solution = p.expandBuffer(dst)
+	p.setSolution(p.expandBuffer(dst))
 	p.Scrambled = false
 	return nil
 }
 
+// Lock scrambles the solution with the given key, as AcrossLite does,
+// so that it can be recovered only with UnlockWithKey or Unlock.
+// Only squares whose solution is a letter from A to Z are scrambled;
+// digits, symbols, and other characters are left in place.
+// Puzzles with rebus squares cannot be locked,
+// since the rebus entries would reveal their answers.
+// The scrambled checksum and the header checksums are updated accordingly.
+func (p *Puzzle) Lock(k int) error {
+	if p.Scrambled {
+		return fmt.Errorf("puzzle is already locked")
+	}
+	if p.hasRebus() {
+		return fmt.Errorf("cannot lock puzzle with rebus squares")
+	}
+	if k == 0 {
+		return fmt.Errorf("0000 is not a valid key")
+	}
+	key, err := NewKeyFromInt(k)
+	if err != nil {
+		return err
+	}
+	src, err := p.scrambledLetters()
+	if err != nil {
+		return err
+	}
+	dst := make([]byte, len(src))
+	tmp := make([]byte, len(src))
+	scramble(src, key, dst, tmp)
+	p.setSolution(p.expandBuffer(dst))
+	p.Checksum.Scrambled = checksum(src, 0)
+	p.Scrambled = true
+	_, err = p.FixChecksums()
+	return err
+}
+
+// hasRebus reports whether any square of the puzzle is a rebus.
+func (p *Puzzle) hasRebus() bool {
+	for y := 0; y < p.Height; y++ {
+		for x := 0; x < p.Width; x++ {
+			if p.IsRebus(x, y) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// scramble is the inverse of unscramble.
+func scramble(src []byte, key Key, dst []byte, tmp []byte) {
+	n := len(src)
+	copy(dst, src)
+	for i := 0; i < 4; i++ {
+		shift(dst, key)
+		k := int(key[i])
+		copy(tmp, dst[k:])
+		copy(tmp[n-k:], dst[:k])
+		shuffle(tmp, dst)
+	}
+}
+
+func shuffle(src []byte, dst []byte) {
+	n := len(src)
+	j := 0
+	for i := 1; i < n; i += 2 {
+		dst[i] = src[j]
+		j++
+	}
+	for i := 0; i < n; i += 2 {
+		dst[i] = src[j]
+		j++
+	}
+}
+
+func shift(buf []byte, key Key) {
+	for i, c := range buf {
+		buf[i] = 'A' + (c-'A'+key[i%4])%26
+	}
+}
+
 func unscramble(src []byte, key Key, dst []byte, tmp []byte) {
 	n := len(src)
 	copy(dst, src)
@@ -127,11 +367,32 @@ func unshift(buf []byte, key Key) {
 	}
 }
 
+// minScrambledLetters is the smallest number of letters that can be scrambled
+// with any key, since each key digit rotates the letters by up to 9 positions.
+const minScrambledLetters = 9
+
+// isScrambledLetter reports whether c takes part in scrambling.
+// Non-letters are never changed by scrambling, so this holds for
+// the same squares of a puzzle whether or not it is locked.
+func isScrambledLetter(c byte) bool {
+	return 'A' <= c && c <= 'Z'
+}
+
+// scrambledLetters returns the letters of the solution that take part in scrambling,
+// in column-major order.
+func (p *Puzzle) scrambledLetters() ([]byte, error) {
+	buf := p.compressBuffer(p.solution)
+	if len(buf) < minScrambledLetters {
+		return nil, fmt.Errorf("puzzle has only %d letters to scramble", len(buf))
+	}
+	return buf, nil
+}
+
 func (p *Puzzle) compressBuffer(g Grid) []byte {
 	var buf bytes.Buffer
 	for x := 0; x < p.Width; x++ {
 		for y := 0; y < p.Height; y++ {
-			if p.IsBlack(x, y) {
+			if !isScrambledLetter(g[y][x]) {
 				continue
 			}
 			buf.WriteByte(g[y][x])
@@ -140,21 +401,32 @@ func (p *Puzzle) compressBuffer(g Grid) []byte {
 	return buf.Bytes()
 }
 
+// expandBuffer returns a copy of the solution with the letters
+// that take part in scrambling replaced by those in buf.
 func (p *Puzzle) expandBuffer(buf []byte) Grid {
 	g := p.MakeGrid()
 	for x := 0; x < p.Width; x++ {
 		for y := 0; y < p.Height; y++ {
-			if p.IsBlack(x, y) {
-				g[y][x] = blackSquare
-			} else {
-				g[y][x] = buf[0]
+			c := p.solution[y][x]
+			if isScrambledLetter(c) {
+				c = buf[0]
 				buf = buf[1:]
 			}
+			g[y][x] = c
 		}
 	}
 	return g
 }
 
+// setSolution replaces the solution and, if the clues have been indexed,
+// re-indexes them so that their answers match it.
+func (p *Puzzle) setSolution(g Grid) {
+	p.solution = g
+	if p.Dir != nil {
+		p.indexClues()
+	}
+}
+
 func (p *Puzzle) correctAnswers(buf []byte) bool {
 	return checksum(buf, 0) == p.Checksum.Scrambled
 }
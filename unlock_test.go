@@ -2,8 +2,11 @@ package crossword
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"path"
+	"strings"
 	"testing"
 )
 
@@ -136,6 +139,266 @@ func TestUnlock(t *testing.T) {
 	}
 }
 
+func TestLock(t *testing.T) {
+	for _, c := range unlockCases {
+		t.Run(c.file, func(t *testing.T) {
+			locked, err := Read(path.Join(testDataDir, c.file))
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			p, err := Read(path.Join(testDataDir, c.file))
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			err = p.UnlockWithKey(c.key)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			err = p.Lock(c.key)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			if !p.Scrambled {
+				t.Errorf("Scrambled flag is still false")
+			}
+			if p.Solution() != locked.Solution() {
+				t.Errorf("locked solution is not correct")
+			}
+			if p.Checksum != locked.Checksum {
+				t.Errorf("checksums == %+v, want %+v", p.Checksum, locked.Checksum)
+			}
+		})
+	}
+}
+
+func TestLockUnlock(t *testing.T) {
+	for _, base := range testFiles() {
+		t.Run(base, func(t *testing.T) {
+			p, err := Read(path.Join(testDataDir, base))
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			if p.Scrambled || p.hasRebus() {
+				return
+			}
+			want := p.Solution()
+			key := 1234
+			err = p.Lock(key)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			puz, err := Encode(p)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			q, err := Decode(puz)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			if !q.Scrambled {
+				t.Errorf("decoded puzzle is not locked")
+			}
+			if q.Solution() == want {
+				t.Errorf("locked solution is the same as the unlocked one")
+			}
+			err = q.UnlockWithKey(key)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			if q.Solution() != want {
+				t.Errorf("unlocked solution is not correct")
+			}
+		})
+	}
+}
+
+func TestLockUnlockAnswers(t *testing.T) {
+	for _, c := range unlockCases {
+		t.Run(c.file, func(t *testing.T) {
+			p, err := Read(path.Join(testDataDir, c.file))
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			locked := strings.Split(p.Solution(), "\n")
+			err = p.UnlockWithKey(c.key)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			checkAcrossAnswers(t, "unlocked", p, strings.Split(c.unlocked, "\n"))
+			err = p.Lock(c.key)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			checkAcrossAnswers(t, "locked", p, locked)
+		})
+	}
+}
+
+// checkAcrossAnswers checks that the across answers of p match the rows of the solution.
+func checkAcrossAnswers(t *testing.T, state string, p *Puzzle, rows []string) {
+	t.Helper()
+	d := p.Dir[Across]
+	for _, n := range d.Numbers {
+		var want []byte
+		for _, pos := range d.Words[n] {
+			want = append(want, rows[pos.Y][pos.X])
+		}
+		if d.Answers[n] != string(want) {
+			t.Errorf("%s answer for %d %v == %q, want %q", state, n, Across, d.Answers[n], want)
+		}
+	}
+}
+
+func TestUnlockContext(t *testing.T) {
+	for _, c := range unlockCases {
+		for _, workers := range []int{0, 1, 3} {
+			t.Run(fmt.Sprintf("%s/%d", c.file, workers), func(t *testing.T) {
+				p, err := Read(path.Join(testDataDir, c.file))
+				if err != nil {
+					t.Errorf("%s", err)
+					return
+				}
+				last := 0
+				progress := func(tried, total int) {
+					if tried <= last || tried > total {
+						t.Errorf("progress reported %d of %d keys after %d", tried, total, last)
+					}
+					last = tried
+				}
+				opts := UnlockOptions{Workers: workers, Progress: progress}
+				key, err := p.UnlockContext(context.Background(), opts)
+				if err != nil {
+					t.Errorf("%s", err)
+					return
+				}
+				if p.Scrambled {
+					t.Errorf("Scrambled flag is still true")
+				}
+				if p.Solution() != c.unlocked {
+					t.Errorf("unlocked solution is not correct")
+				}
+				if key != c.key {
+					t.Errorf("parallel unlock found key %04d, want %04d", key, c.key)
+				}
+				if last < c.key/keyBatchSize*keyBatchSize {
+					t.Errorf("progress reported only %d keys tried", last)
+				}
+			})
+		}
+	}
+}
+
+func TestUnlockContextCanceled(t *testing.T) {
+	p, err := Read(path.Join(testDataDir, unlockCases[0].file))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = p.UnlockContext(ctx, UnlockOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("UnlockContext returned %v, want %v", err, context.Canceled)
+	}
+	if !p.Scrambled {
+		t.Errorf("Scrambled flag is false after canceled unlock")
+	}
+}
+
+func TestLockNonLetters(t *testing.T) {
+	solution := Grid{
+		[]byte("R2D2.C3PO"),
+		[]byte("ABCDEFGHI"),
+		[]byte("#1.JKLMN&"),
+	}
+	p := &Puzzle{Width: 9, Height: 3, solution: solution}
+	want := p.Solution()
+	const key = 7284
+	err := p.Lock(key)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for y := range solution {
+		for x, c := range solution[y] {
+			got := p.solution[y][x]
+			if isScrambledLetter(c) {
+				if !isScrambledLetter(got) {
+					t.Errorf("letter %q at %v scrambled to %q", c, NewPosition(x, y), got)
+				}
+			} else if got != c {
+				t.Errorf("non-letter %q at %v scrambled to %q", c, NewPosition(x, y), got)
+			}
+		}
+	}
+	if p.Solution() == want {
+		t.Errorf("locked solution is the same as the unlocked one")
+	}
+	locked := p.solution
+	unlockers := []struct {
+		name   string
+		unlock func() error
+	}{
+		{"UnlockWithKey", func() error { return p.UnlockWithKey(key) }},
+		{"Unlock", func() error { _, err := p.Unlock(); return err }},
+		{"UnlockContext", func() error { _, err := p.UnlockContext(context.Background(), UnlockOptions{}); return err }},
+	}
+	for _, u := range unlockers {
+		t.Run(u.name, func(t *testing.T) {
+			p.solution = locked
+			p.Scrambled = true
+			err := u.unlock()
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			if p.Solution() != want {
+				t.Errorf("unlocked solution == %q, want %q", p.Solution(), want)
+			}
+		})
+	}
+}
+
+func TestLockTooFewLetters(t *testing.T) {
+	p := &Puzzle{Width: 3, Height: 3, solution: Grid{
+		[]byte("A1B"),
+		[]byte("2.3"),
+		[]byte("C4D"),
+	}}
+	err := p.Lock(1234)
+	if err == nil {
+		t.Errorf("Lock succeeded with only 4 letters")
+	}
+	if p.Scrambled {
+		t.Errorf("Scrambled flag is true after failed lock")
+	}
+}
+
+func TestLockRebus(t *testing.T) {
+	p, err := Read(path.Join(testDataDir, "Mar2711.puz"))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	want := p.Solution()
+	err = p.Lock(1234)
+	if err == nil {
+		t.Errorf("Lock succeeded for puzzle with rebus squares")
+	}
+	if p.Scrambled || p.Solution() != want {
+		t.Errorf("puzzle was modified by failed lock")
+	}
+}
+
 func TestUnlockAllPuzzles(t *testing.T) {
 	for _, base := range testFiles() {
 		t.Run(base, func(t *testing.T) {
@@ -261,11 +524,18 @@ func BenchmarkUnlock(b *testing.B) {
 		return
 	}
 	orig := p.solution
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		p.Unlock()
-		p.solution = orig
-		p.Scrambled = true
-	}
-
+	b.Run("Sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			p.Unlock()
+			p.solution = orig
+			p.Scrambled = true
+		}
+	})
+	b.Run("Parallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			p.UnlockContext(context.Background(), UnlockOptions{})
+			p.solution = orig
+			p.Scrambled = true
+		}
+	})
 }
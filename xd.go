@@ -0,0 +1,257 @@
+package crossword
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// xdBlock is the character for black squares in the xd format.
+const xdBlock = '#'
+
+// xdClue holds a clue in xd format.
+type xdClue struct {
+	line   int
+	text   string
+	answer string
+}
+
+// ReadXD reads the named file in xd format.
+func ReadXD(file string) (*Puzzle, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	p, err := DecodeXD(data)
+	if err != nil {
+		err = fmt.Errorf("%s: %w", file, err)
+	}
+	return p, err
+}
+
+// DecodeXD decodes a puzzle in xd format, which consists of
+// header lines of the form "Key: value", the grid, the clues in the form
+// "A1. Clue ~ ANSWER", and optional notes, separated by blank lines.
+// The Title, Author, Copyright, Rebus, and Notes headers are used; others are ignored.
+// Lowercase letters in the grid are circled, and the notes become the notepad.
+// Each answer is checked against the grid.
+func DecodeXD(data []byte) (*Puzzle, error) {
+	lines := strings.Split(strings.ReplaceAll(textString(data), "\r\n", "\n"), "\n")
+	var p Puzzle
+	i := skipBlankLines(lines, 0)
+	headers := make(map[string]string)
+	if i < len(lines) && strings.Contains(lines[i], ":") {
+		for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+			f := strings.SplitN(lines[i], ":", 2)
+			if len(f) != 2 {
+				return nil, fmt.Errorf("line %d: malformed header %q", i+1, lines[i])
+			}
+			headers[strings.ToLower(strings.TrimSpace(f[0]))] = strings.TrimSpace(f[1])
+		}
+	}
+	p.Title = headers["title"]
+	p.Author = headers["author"]
+	p.Copyright = headers["copyright"]
+	p.Notepad = headers["notes"]
+	rebus, err := readXDRebus(headers["rebus"])
+	if err != nil {
+		return nil, err
+	}
+	i = skipBlankLines(lines, i)
+	grid := &textSection{line: i}
+	for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+		row := strings.TrimSpace(lines[i])
+		row = strings.NewReplacer(string(xdBlock), string(blackSquare), "_", string(blackSquare)).Replace(row)
+		grid.lines = append(grid.lines, row)
+	}
+	if len(grid.lines) == 0 {
+		return nil, fmt.Errorf("missing grid")
+	}
+	p.Width, p.Height = len(grid.lines[0]), len(grid.lines)
+	err = p.readTextGrid(grid, rebus, true)
+	if err != nil {
+		return nil, err
+	}
+	clues := []map[int]xdClue{make(map[int]xdClue), make(map[int]xdClue)}
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		dir, n, c, ok := parseXDClue(line)
+		if !ok {
+			break
+		}
+		if _, dup := clues[dir][n]; dup {
+			return nil, fmt.Errorf("line %d: duplicate clue for %d %v", i+1, n, dir)
+		}
+		c.line = i + 1
+		clues[dir][n] = c
+	}
+	if notes := strings.TrimSpace(strings.Join(lines[i:], "\n")); notes != "" {
+		p.Notepad = notes
+	}
+	err = p.readXDClues(clues)
+	if err != nil {
+		return nil, err
+	}
+	p.setVersion()
+	return &p, nil
+}
+
+func skipBlankLines(lines []string, i int) int {
+	for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+		i++
+	}
+	return i
+}
+
+// readXDRebus parses the value of the Rebus header,
+// which contains space-separated entries of the form "1=REBUS".
+func readXDRebus(s string) (map[byte][2]string, error) {
+	rebus := make(map[byte][2]string)
+	for _, e := range strings.Fields(s) {
+		f := strings.SplitN(e, "=", 2)
+		if len(f) != 2 || len(f[0]) != 1 || f[1] == "" {
+			return nil, fmt.Errorf("malformed rebus entry %q", e)
+		}
+		c := f[0][0]
+		if isLetter(c) || c == xdBlock {
+			return nil, fmt.Errorf("rebus marker %q is not allowed", c)
+		}
+		long := strings.ToUpper(f[1])
+		rebus[c] = [2]string{long, long[:1]}
+	}
+	return rebus, nil
+}
+
+// parseXDClue parses a line of the form "A1. Clue ~ ANSWER".
+func parseXDClue(line string) (Direction, int, xdClue, bool) {
+	var dir Direction
+	if line == "" {
+		return dir, 0, xdClue{}, false
+	}
+	switch line[0] {
+	case 'A':
+		dir = Across
+	case 'D':
+		dir = Down
+	default:
+		return dir, 0, xdClue{}, false
+	}
+	i := strings.IndexByte(line, '.')
+	if i == -1 {
+		return dir, 0, xdClue{}, false
+	}
+	n, err := strconv.Atoi(line[1:i])
+	if err != nil {
+		return dir, 0, xdClue{}, false
+	}
+	var c xdClue
+	rest := line[i+1:]
+	if j := strings.LastIndex(rest, " ~ "); j != -1 {
+		rest, c.answer = rest[:j], strings.TrimSpace(rest[j+3:])
+	}
+	c.text = strings.TrimSpace(rest)
+	return dir, n, c, true
+}
+
+// readXDClues sets the clues of the puzzle and checks their answers against the grid.
+func (p *Puzzle) readXDClues(clues []map[int]xdClue) error {
+	for _, e := range p.clueEntries() {
+		c, ok := clues[e.dir][e.number]
+		if !ok {
+			return fmt.Errorf("no clue for %d %v", e.number, e.dir)
+		}
+		p.AllClues = append(p.AllClues, c.text)
+	}
+	p.NumClues = len(p.AllClues)
+	p.indexClues()
+	for dir, m := range clues {
+		d := p.Dir[dir]
+		for n, c := range m {
+			answer, ok := d.Answers[n]
+			if !ok {
+				return fmt.Errorf("line %d: clue for %d %v does not match any word", c.line, n, Direction(dir))
+			}
+			if c.answer != "" && !strings.EqualFold(c.answer, answer) {
+				return fmt.Errorf("line %d: answer %q for %d %v does not match grid %q", c.line, c.answer, n, Direction(dir), answer)
+			}
+		}
+	}
+	return nil
+}
+
+// WriteXD encodes the puzzle in xd format and writes it to the named file.
+func WriteXD(file string, p *Puzzle) error {
+	data, err := EncodeXD(p)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// EncodeXD returns the xd representation of the puzzle, encoded in UTF-8.
+// Circled squares are shown as lowercase letters, except for rebus squares,
+// and the notepad is written as the notes section.
+func EncodeXD(p *Puzzle) ([]byte, error) {
+	if p.Scrambled {
+		return nil, fmt.Errorf("cannot encode locked puzzle as xd")
+	}
+	if !p.hasGridSize(p.solution) {
+		return nil, fmt.Errorf("solution does not match %d×%d puzzle", p.Width, p.Height)
+	}
+	entries := p.clueEntries()
+	if len(entries) != len(p.AllClues) {
+		return nil, fmt.Errorf("puzzle has %d clues for %d entries", len(p.AllClues), len(entries))
+	}
+	grid, rebus, mark, err := p.textGrid(xdBlock)
+	if err != nil {
+		return nil, err
+	}
+	var buf strings.Builder
+	writeXDHeader(&buf, "Title", p.Title)
+	writeXDHeader(&buf, "Author", p.Author)
+	writeXDHeader(&buf, "Copyright", p.Copyright)
+	if len(rebus) != 0 {
+		var v []string
+		for _, r := range rebus {
+			v = append(v, fmt.Sprintf("%c=%s", r.marker, r.answer))
+		}
+		writeXDHeader(&buf, "Rebus", strings.Join(v, " "))
+	}
+	if mark {
+		writeXDHeader(&buf, "Special", "circle")
+	}
+	buf.WriteString("\n\n")
+	for _, row := range grid {
+		buf.WriteString(row + "\n")
+	}
+	for _, dir := range []Direction{Across, Down} {
+		buf.WriteString("\n")
+		if dir == Across {
+			buf.WriteString("\n")
+		}
+		d := p.Dir[dir]
+		for i, e := range entries {
+			if e.dir != dir {
+				continue
+			}
+			answer := p.wordAnswer(d.Words[e.number])
+			fmt.Fprintf(&buf, "%c%d. %s ~ %s\n", dir.String()[0], e.number, p.AllClues[i], answer)
+		}
+	}
+	if notes := strings.TrimSpace(strings.ReplaceAll(p.Notepad, "\r\n", "\n")); notes != "" {
+		buf.WriteString("\n\n" + notes + "\n")
+	}
+	return []byte(buf.String()), nil
+}
+
+func writeXDHeader(buf *strings.Builder, key, value string) {
+	value = strings.TrimSpace(strings.ReplaceAll(value, "\n", " "))
+	if value == "" {
+		return
+	}
+	fmt.Fprintf(buf, "%s: %s\n", key, value)
+}
@@ -0,0 +1,144 @@
+package crossword
+
+import (
+	"path"
+	"strings"
+	"testing"
+)
+
+const sampleXD = `Title: Sample Puzzle
+Author: A. Constructor
+Editor: A. N. Editor
+Copyright: © 2024 Example Syndicate
+Rebus: 1=CAT
+Special: circle
+
+
+1aTS
+ROBE
+IRON
+#NEW
+
+
+A1. Felines plus fish? ~ CATATS
+A5. Bathrobe, e.g. ~ ROBE
+A6. Press ~ IRON
+A7. Recent ~ NEW
+
+D1. Animal doctor's charge? ~ CATRI
+D2. Throw ~ AORN
+D3. Attempts ~ TBOE
+D4. Stitched ~ SENW
+
+
+First line of the notepad.
+
+Last line.
+`
+
+func TestDecodeXD(t *testing.T) {
+	p, err := DecodeXD([]byte(sampleXD))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	q, err := DecodeText([]byte(sampleText))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	checkSamePuzzle(t, p, q)
+	if !p.IsRebus(0, 0) || p.AnswerString(0, 0) != "CAT" {
+		t.Errorf("square (0, 0) == %q, want rebus %q", p.AnswerString(0, 0), "CAT")
+	}
+}
+
+func TestEncodeXD(t *testing.T) {
+	p, err := DecodeText([]byte(sampleText))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	data, err := EncodeXD(p)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	want := strings.Replace(sampleXD, "Editor: A. N. Editor\n", "", 1)
+	if string(data) != want {
+		t.Errorf("EncodeXD == %q, want %q", data, want)
+	}
+}
+
+func TestEncodeXDAllPuzzles(t *testing.T) {
+	for _, base := range testFiles() {
+		t.Run(base, func(t *testing.T) {
+			p, err := Read(path.Join(testDataDir, base))
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			if p.Scrambled {
+				return
+			}
+			data, err := EncodeXD(p)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			q, err := DecodeXD(data)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			checkSamePuzzle(t, q, p)
+		})
+	}
+}
+
+func TestEncodeXDUnlocked(t *testing.T) {
+	p, err := Read(path.Join(testDataDir, "Apr2510.puz"))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	err = p.UnlockWithKey(4462)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	data, err := EncodeXD(p)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !strings.Contains(string(data), " ~ CDROM\n") {
+		t.Errorf("EncodeXD output does not contain unlocked answer %q", "CDROM")
+	}
+	q, err := DecodeXD(data)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	checkSamePuzzle(t, q, p)
+}
+
+func TestDecodeXDErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		edit func(string) string
+		want string
+	}{
+		{"no grid", func(s string) string { return s[:strings.Index(s, "1aTS")] }, "missing grid"},
+		{"short row", func(s string) string { return strings.Replace(s, "ROBE", "ROB", 1) }, "3 columns instead of 4"},
+		{"bad rebus", func(s string) string { return strings.Replace(s, "1=CAT", "1CAT", 1) }, "malformed rebus entry"},
+		{"wrong answer", func(s string) string { return strings.Replace(s, "~ IRON", "~ ICON", 1) }, `answer "ICON" for 6 ACROSS does not match grid "IRON"`},
+		{"missing clue", func(s string) string { return strings.Replace(s, "A7. Recent ~ NEW\n", "", 1) }, "no clue for 7 ACROSS"},
+		{"extra clue", func(s string) string { return strings.Replace(s, "A7.", "A8. Extra\nA7.", 1) }, "does not match any word"},
+		{"duplicate clue", func(s string) string { return strings.Replace(s, "A7.", "A6. Again\nA7.", 1) }, "duplicate clue for 6 ACROSS"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := DecodeXD([]byte(c.edit(sampleXD)))
+			if err == nil {
+				t.Errorf("DecodeXD succeeded, want error containing %q", c.want)
+				return
+			}
+			if !strings.Contains(err.Error(), c.want) {
+				t.Errorf("DecodeXD returned %q, want error containing %q", err, c.want)
+			}
+		})
+	}
+}